@@ -2,20 +2,57 @@ package ui
 
 import "image"
 
+// dispatcher holds the state of Dispatch which Controllers can change.
+type dispatcher struct {
+	root       *Box
+	keyFocus   *Box
+	mouseFocus *Box
+	// the subtrees the focus is trapped in, innermost last
+	scopes []scope
+	// set when a Tab moved the focus, so that its KeyUp does not go to
+	// the view it moved to
+	tabbed bool
+}
+
+// Phase is the stage of routing an event is in, as reported by
+// Controller.Phase.
+type Phase int
+
+const (
+	// TargetPhase is the event reaching the view it is meant for, and
+	// the phase of events which are not routed.
+	TargetPhase Phase = iota
+	// CapturePhase is the event passing down from the root to the
+	// target, through the Capturers on the way.
+	CapturePhase
+	// BubblePhase is the event passing back up from the target to the
+	// root.
+	BubblePhase
+)
+
 // master component is generally responsible for doing layout and drawing
 // to screen. sized to the Environment.
-
+//
+// Key events go to the view with the keyboard focus, and mouse events,
+// including Scroll, to the view under the cursor, or the root if there
+// is none. Either is
+// routed in three phases: capture, from the root down to the target's
+// parent, the target itself, and then bubbling back up to the root.
+// Any view can stop the rest of the routing with
+// Controller.StopPropagation, which also keeps a left button press
+// from moving the focus. Other events only go to their target.
+//
+// Tab and $(tab) move the keyboard focus between the Focusable views,
+// unless a view stops their propagation. The KeyUp of a Tab which moved
+// the focus is not sent on.
 func Dispatch(env Environment, master Component) error {
-	var (
-		root       *Box
-		keyFocus   *Box
-		mouseFocus *Box
-	)
+	d := &dispatcher{}
 	{
 		w, h, _ := env.Size()
-		setup(nil, image.Rect(0, 0, w, h), master)
-		root = master.box()
+		setup(d, nil, image.Rect(0, 0, w, h), master)
+		d.root = master.box()
 	}
+	root := d.root
 	for {
 		event, ok := env.Listen()
 		if !ok {
@@ -23,39 +60,121 @@ func Dispatch(env Environment, master Component) error {
 			return nil
 		}
 		switch e := event.(type) {
-		case KeyDown, KeyUp, KeyRepeat, UnicodeTyped:
-			if keyFocus != nil {
-				keyFocus.send(e)
+		case KeyDown:
+			d.key(e.Key, e)
+		case KeyRepeat:
+			d.key(e.Key, e)
+		case KeyUp:
+			if d.tabbed && e.TrimShift() == Tab {
+				d.tabbed = false
+				break
 			}
+			d.keyTarget().route(e)
+		case UnicodeTyped:
+			d.keyTarget().route(e)
 		case MouseUpdate:
-			target := mouseFocus
-			if !e.Left {
+			// the view a button was pressed on keeps the mouse until
+			// all buttons are released
+			target := d.mouseFocus
+			if !e.Pressed() {
 				target = root.hitTest(e.Point)
 			}
-			if target != mouseFocus {
-				if mouseFocus != nil {
-					mouseFocus.send(e)
-					mouseFocus.send(MouseLeave{})
+			if target != d.mouseFocus {
+				if d.mouseFocus != nil {
+					d.mouseFocus.send(e)
+					d.mouseFocus.send(MouseLeave{})
 				}
 				if target != nil {
 					target.send(MouseEnter{})
 				}
-				mouseFocus = target
+				d.mouseFocus = target
 			}
-			if target != nil {
-				target.send(e)
-				if e.Left && target != keyFocus {
-					if keyFocus != nil {
-						keyFocus.send(FocusLost{})
-					}
-					keyFocus = target
-					keyFocus.send(FocusGained{})
-				}
+			if target == nil {
+				root.route(e)
+				break
 			}
+			if !target.route(e) && e.Left {
+				d.focus(target)
+			}
+		case Scroll:
+			target := d.mouseFocus
+			if target == nil || !e.Pressed() {
+				target = root.hitTest(e.Point)
+			}
+			if target == nil {
+				target = root
+			}
+			target.route(e)
 		case SizeUpdate:
 			root.bounds = image.Rect(0, 0, e.Width, e.Height)
+			root.send(e)
+		default:
+			root.send(e)
+		}
+	}
+}
+
+// keyTarget returns the Box key events go to.
+func (d *dispatcher) keyTarget() *Box {
+	if d.keyFocus != nil {
+		return d.keyFocus
+	}
+	return d.root
+}
+
+// key routes a KeyDown or KeyRepeat of k, and then moves the focus if
+// it is an unstopped Tab.
+func (d *dispatcher) key(k Key, event interface{}) {
+	if d.keyTarget().route(event) || k.TrimShift() != Tab {
+		return
+	}
+	old := d.keyFocus
+	d.tab(k.Shift())
+	if d.keyFocus != old {
+		d.tabbed = true
+	}
+}
+
+// route sends event through the capture, target and bubble phases, and
+// reports whether a view stopped it.
+func (b *Box) route(event interface{}) (stopped bool) {
+	var path []*Box
+	for p := b.parent; p != nil; p = p.parent {
+		path = append(path, p)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].deliver(CapturePhase, event) {
+			return true
+		}
+	}
+	if b.deliver(TargetPhase, event) {
+		return true
+	}
+	for _, p := range path {
+		if p.deliver(BubblePhase, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends event to the Component of b in the given phase, and
+// reports whether it stopped the event. Only Capturers take part in
+// the capture phase.
+func (b *Box) deliver(phase Phase, event interface{}) (stopped bool) {
+	c := b.ctl
+	if c == nil {
+		return false
+	}
+	c.phase, c.stopped = phase, false
+	if phase == CapturePhase {
+		if capt, ok := c.comp.(Capturer); ok {
+			capt.Capture(c, event)
 		}
-		root.send(event)
+	} else {
+		c.comp.Receive(c, event)
 	}
-	return nil
+	stopped = c.stopped
+	c.phase, c.stopped = TargetPhase, false
+	return stopped
 }
@@ -2,95 +2,322 @@ package ui_test
 
 import (
 	"fmt"
-	"runtime"
+	"image"
+	"reflect"
 	"testing"
 
-	"j4k.co/ui"
+	"j4k.co/exp/ui"
+	"j4k.co/exp/ui/uitest"
 )
 
-type env struct {
-	list []interface{}
+type eventChecker struct {
+	ui.Box
+	events []interface{}
 }
 
-func testEnv(events []interface{}) ui.Environment {
-	return &env{
-		list: events,
-	}
+func (e *eventChecker) Receive(ctl *ui.Controller, event interface{}) {
+	e.events = append(e.events, event)
 }
 
-func (e *env) Size() (w, h int, pixelRatio float32) {
-	return 100, 100, 1
+func TestDispatchEvents(t *testing.T) {
+	env := uitest.New(100, 100)
+	master := &eventChecker{}
+	env.Start(master)
+	env.Resize(110, 110)
+	env.Resize(120, 120)
+	if err := env.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		ui.Mount{},
+		ui.SizeUpdate{Width: 110, Height: 110},
+		ui.SizeUpdate{Width: 120, Height: 120},
+		ui.Unmount{},
+	}
+	if !reflect.DeepEqual(expected, master.events) {
+		t.Fatalf("expected events %#v, got %#v", expected, master.events)
+	}
+	if master.Bounds() != image.Rect(0, 0, 120, 120) {
+		t.Fatalf("expected master to be resized, got %v", master.Bounds())
+	}
 }
 
-func (e *env) Listen() (event interface{}, ok bool) {
-	if len(e.list) == 0 {
-		return nil, false
+func TestDispatchFocus(t *testing.T) {
+	env := uitest.New(100, 100)
+	a, b := &eventChecker{}, &eventChecker{}
+	master := &mounter{views: []ui.View{a, b}}
+	env.Start(master)
+	a.SetBounds(image.Rect(0, 0, 50, 100))
+	b.SetBounds(image.Rect(50, 0, 100, 100))
+
+	env.Click(image.Pt(10, 10))
+	if uitest.Focused(master) != a {
+		t.Fatalf("expected a to have focus")
 	}
-	event = e.list[0]
-	ok = true
-	e.list = e.list[1:]
-	return
+	env.Press("x")
+	env.Click(image.Pt(60, 10))
+	if uitest.Focused(master) != b || a.Focused() {
+		t.Fatalf("expected focus to move to b")
+	}
+	if !containsEvent(a.events, ui.KeyDown{Key: "x"}) || containsEvent(b.events, ui.KeyDown{Key: "x"}) {
+		t.Fatalf("expected key events to go to the focused view")
+	}
+	if !containsEvent(a.events, ui.FocusLost{}) || !containsEvent(b.events, ui.FocusGained{}) {
+		t.Fatalf("expected focus events")
+	}
+	env.Close()
 }
 
-type eventChecker struct {
+type mounter struct {
 	ui.Box
-	t      *testing.T
-	events []interface{}
-	done   chan<- error
+	ctl   *ui.Controller
+	views []ui.View
 }
 
-func (e *eventChecker) Run(ctl *ui.Controller) {
-	for {
-		event, ok := ctl.Listen()
-		if ok && len(e.events) == 0 {
-			e.fatalf("expected end of listener")
+func (m *mounter) Receive(ctl *ui.Controller, event interface{}) {
+	m.ctl = ctl
+	if _, ok := event.(ui.Mount); ok {
+		ctl.Mount(m.views...)
+	}
+}
+
+func containsEvent(events []interface{}, event interface{}) bool {
+	for _, e := range events {
+		if e == event {
+			return true
 		}
-		if !ok && len(e.events) > 0 {
-			e.fatalf("unexpected end of listener")
+	}
+	return false
+}
+
+type field struct {
+	eventChecker
+	ctl      *ui.Controller
+	disabled bool
+}
+
+func (f *field) Receive(ctl *ui.Controller, event interface{}) {
+	f.ctl = ctl
+	f.eventChecker.Receive(ctl, event)
+}
+
+func (f *field) Focusable() bool {
+	return !f.disabled
+}
+
+// countEvents counts the events of the same type as event.
+func countEvents(events []interface{}, event interface{}) int {
+	n := 0
+	for _, e := range events {
+		if reflect.TypeOf(e) == reflect.TypeOf(event) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDispatchTab(t *testing.T) {
+	a, b, c := &field{}, &field{disabled: true}, &field{}
+	dialog := &mounter{views: []ui.View{c}}
+	master := &mounter{views: []ui.View{a, &mounter{views: []ui.View{b}}, dialog}}
+	env := uitest.New(100, 100)
+	env.Start(master)
+	defer env.Close()
+
+	order := []ui.View{a, c, a, c}
+	for i, v := range order {
+		env.Press(ui.Tab)
+		if uitest.Focused(master) != v {
+			t.Fatalf("tab %d: expected focus on %d", i, i%2)
 		}
-		if !ok {
-			break
+	}
+	env.Press(ui.Shift + ui.Tab)
+	if uitest.Focused(master) != a {
+		t.Fatalf("expected shift-tab to move the focus back")
+	}
+	if !containsEvent(a.events, ui.KeyDown{Key: ui.Tab}) {
+		t.Fatalf("expected tab to be sent to the focused view")
+	}
+	if containsEvent(a.events, ui.KeyUp{Key: ui.Tab}) || containsEvent(c.events, ui.KeyUp{Key: ui.Tab}) {
+		t.Fatalf("expected the release of a tab which moved the focus not to be sent")
+	}
+	if n, m := countEvents(a.events, ui.FocusGained{}), countEvents(a.events, ui.FocusLost{}); n != 3 || m != 2 {
+		t.Fatalf("expected 3 FocusGained and 2 FocusLost, got %d and %d", n, m)
+	}
+
+	c.ctl.Focus()
+	if uitest.Focused(master) != c || a.Focused() {
+		t.Fatalf("expected Focus to move the focus")
+	}
+	c.ctl.Blur()
+	if uitest.Focused(master) != nil || countEvents(c.events, ui.FocusLost{}) != 3 {
+		t.Fatalf("expected Blur to remove the focus")
+	}
+}
+
+func TestDispatchTrapFocus(t *testing.T) {
+	a, b, c := &field{}, &field{}, &field{}
+	dialog := &field{}
+	dialog.disabled = true
+	master := &mounter{views: []ui.View{a, dialog}}
+	env := uitest.New(100, 100)
+	env.Start(master)
+	defer env.Close()
+	a.ctl.Focus()
+	dialog.ctl.Mount(b, c)
+	a.SetBounds(image.Rect(0, 0, 10, 10))
+	dialog.SetBounds(image.Rect(50, 50, 100, 100))
+	b.SetBounds(image.Rect(70, 70, 80, 80))
+	c.SetBounds(image.Rect(80, 80, 90, 90))
+
+	dialog.ctl.TrapFocus()
+	if uitest.Focused(master) != b {
+		t.Fatalf("expected the focus to move into the dialog")
+	}
+	for i, v := range []ui.View{c, b, c} {
+		env.Press(ui.Tab)
+		if uitest.Focused(master) != v {
+			t.Fatalf("tab %d: expected the focus to stay in the dialog", i)
 		}
-		if event != e.events[0] {
-			e.fatalf("expected event %#v, got %#v\n", e.events[0], event)
+	}
+	a.ctl.TrapFocus()
+	if uitest.Focused(master) != c {
+		t.Fatalf("expected a view outside the dialog not to trap the focus")
+	}
+	a.ctl.Focus()
+	env.Click(image.Pt(5, 5))
+	if uitest.Focused(master) != c {
+		t.Fatalf("expected the focus to stay in the dialog")
+	}
+	env.Click(image.Pt(60, 60))
+	if uitest.Focused(master) != dialog {
+		t.Fatalf("expected clicks in the dialog to move the focus")
+	}
+
+	master.ctl.Unmount(dialog)
+	if uitest.Focused(master) != a || !containsEvent(dialog.events, ui.FocusLost{}) {
+		t.Fatalf("expected the focus to return after unmounting the dialog")
+	}
+	if master.Subviews() != 1 {
+		t.Fatalf("expected the dialog to be unmounted")
+	}
+	env.Press(ui.Tab)
+	if uitest.Focused(master) != a {
+		t.Fatalf("expected a to be the only focusable view")
+	}
+}
+
+// router records the events it sees, and stops them in the given phase.
+type router struct {
+	ui.Box
+	name  string
+	stop  ui.Phase
+	log   *[]string
+	views []ui.View
+}
+
+func (r *router) Receive(ctl *ui.Controller, event interface{}) {
+	switch event.(type) {
+	case ui.Mount:
+		ctl.Mount(r.views...)
+	case ui.KeyDown:
+		r.record(ctl)
+	}
+}
+
+func (r *router) Capture(ctl *ui.Controller, event interface{}) {
+	if _, ok := event.(ui.KeyDown); ok {
+		r.record(ctl)
+	}
+}
+
+func (r *router) record(ctl *ui.Controller) {
+	*r.log = append(*r.log, fmt.Sprintf("%s %d", r.name, ctl.Phase()))
+	if ctl.Phase() == r.stop {
+		ctl.StopPropagation()
+	}
+}
+
+func TestDispatchRouting(t *testing.T) {
+	const none = ui.Phase(-1)
+	var log []string
+	leaf := &field{}
+	mid := &router{name: "mid", stop: none, log: &log, views: []ui.View{leaf}}
+	root := &router{name: "root", stop: none, log: &log, views: []ui.View{mid}}
+	env := uitest.New(100, 100)
+	env.Start(root)
+	defer env.Close()
+
+	check := func(expected ...string) {
+		t.Helper()
+		if !reflect.DeepEqual(log, expected) {
+			t.Fatalf("expected %q, got %q", expected, log)
 		}
-		e.events = e.events[1:]
+		log = nil
+	}
+	c, tg, b := ui.CapturePhase, ui.TargetPhase, ui.BubblePhase
+	p := func(name string, phase ui.Phase) string {
+		return fmt.Sprintf("%s %d", name, phase)
+	}
+
+	// with no focus, keys go to the root
+	env.Press("x")
+	check(p("root", tg))
+
+	leaf.ctl.Focus()
+	env.Press("x")
+	check(p("root", c), p("mid", c), p("mid", b), p("root", b))
+	if !containsEvent(leaf.events, ui.KeyDown{Key: "x"}) {
+		t.Fatalf("expected the target to receive the key")
 	}
-	if len(e.events) > 0 {
-		e.fatalf("expeced more events")
+
+	mid.stop = c
+	leaf.events = nil
+	env.Press("x")
+	check(p("root", c), p("mid", c))
+	if containsEvent(leaf.events, ui.KeyDown{Key: "x"}) {
+		t.Fatalf("expected the key to be stopped before the target")
+	}
+
+	mid.stop = b
+	env.Press(ui.Tab)
+	check(p("root", c), p("mid", c), p("mid", b))
+	if !leaf.Focused() {
+		t.Fatalf("expected a stopped tab to leave the focus alone")
 	}
-	e.done <- nil
+	mid.stop = none
+	env.Press(ui.Tab)
+	check(p("root", c), p("mid", c), p("mid", b), p("root", b))
 }
 
-func (e *eventChecker) fatalf(format string, args ...interface{}) {
-	e.done <- fmt.Errorf(format, args...)
-	runtime.Goexit()
+// clickStopper stops mouse events on their way to its subviews.
+type clickStopper struct {
+	mounter
 }
 
-func TestDispatchEvents(t *testing.T) {
-	events := []interface{}{
-		ui.SizeUpdate{
-			Width: 110, Height: 110,
-		},
-		ui.SizeUpdate{
-			Width: 120, Height: 120,
-		},
-	}
-	env := testEnv(events)
-	done := make(chan error, 1)
-	go func() {
-		done <- ui.DispatchEvents(env, &eventChecker{
-			t:      t,
-			events: events,
-			done:   done,
-		})
-	}()
-	err := <-done
-	if err != nil {
-		t.Fatal(err)
+func (s *clickStopper) Capture(ctl *ui.Controller, event interface{}) {
+	if _, ok := event.(ui.MouseUpdate); ok {
+		ctl.StopPropagation()
 	}
-	err = <-done
-	if err != nil {
-		t.Fatal(err)
+}
+
+func TestDispatchStoppedClick(t *testing.T) {
+	a, b := &field{}, &field{}
+	stopper := &clickStopper{mounter{views: []ui.View{b}}}
+	master := &mounter{views: []ui.View{a, stopper}}
+	env := uitest.New(100, 100)
+	env.Start(master)
+	defer env.Close()
+	a.SetBounds(image.Rect(0, 0, 10, 10))
+	stopper.SetBounds(image.Rect(50, 50, 100, 100))
+	b.SetBounds(image.Rect(60, 60, 70, 70))
+
+	env.Click(image.Pt(5, 5))
+	if !a.Focused() {
+		t.Fatalf("expected a click to move the focus")
+	}
+	env.Click(image.Pt(65, 65))
+	if !a.Focused() || b.Focused() {
+		t.Fatalf("expected a stopped click to leave the focus alone")
 	}
 }
@@ -23,7 +23,13 @@ type Unmount struct {
 
 type MouseState struct {
 	image.Point
-	Left, Right bool
+	Left, Right, Middle bool
+	// Extra holds the state of any further buttons, from ButtonMiddle+1
+	// in bit 0 onwards.
+	Extra uint8
+	// Mod holds the modifier keys held down, in the format of Key, eg.
+	// "$^" for Shift and Control.
+	Mod Key
 }
 
 // MouseUpdate given on changes to the position of the cursor, or the
@@ -31,6 +37,19 @@ type MouseState struct {
 type MouseUpdate struct {
 	MouseState
 	Previous MouseState
+	// Clicks counts the presses of a series of clicks, on updates which
+	// press or release a button: 1 for a single click, 2 for a double
+	// click, and so on. It is 0 on other updates.
+	Clicks int
+}
+
+// Scroll given when the scroll wheel is turned, or the trackpad is
+// scrolled, by DX and DY, which are positive for scrolling right and
+// up. A notch of a wheel scrolls by 1, while trackpads give fractions
+// of that.
+type Scroll struct {
+	MouseState
+	DX, DY float64
 }
 
 type MouseEnter struct {
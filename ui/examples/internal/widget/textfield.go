@@ -74,9 +74,9 @@ func (t *TextField) keyboard(k ui.Key) {
 	case ui.Delete:
 		t.forwardDelete()
 	case ui.Left:
-		t.moveBackward(shift)
+		t.moveBackward(k.Shift())
 	case ui.Right:
-		t.moveForward(shift)
+		t.moveForward(k.Shift())
 	default:
 		t.emacs(k)
 	}
@@ -145,3 +145,7 @@ type NumberField struct {
 func (n *NumberField) Receive(ctl *ui.Controller, event interface{}) {
 	n.text.Receive(ctl, event)
 }
+
+func (t *TextField) Focusable() bool {
+	return true
+}
@@ -0,0 +1,76 @@
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"j4k.co/exp/ui"
+	"j4k.co/exp/ui/uitest"
+)
+
+// form lays out its views in a column of rows 20 high.
+type form struct {
+	ui.Box
+	views []ui.View
+}
+
+func (f *form) Receive(ctl *ui.Controller, event interface{}) {
+	if _, ok := event.(ui.Mount); ok {
+		ctl.Mount(f.views...)
+		for i, v := range f.views {
+			v.(interface{ SetBounds(image.Rectangle) }).SetBounds(image.Rect(0, i*20, 100, (i+1)*20))
+		}
+	}
+}
+
+func TestButton(t *testing.T) {
+	clicks := 0
+	b := &Button{Text: "OK", OnClick: func() { clicks++ }}
+	env := uitest.New(100, 100)
+	env.Start(&form{views: []ui.View{b}})
+	defer env.Close()
+
+	env.Move(image.Pt(10, 10))
+	if b.State != Hot {
+		t.Fatalf("expected Hot, got %v", b.State)
+	}
+	env.Click(image.Pt(10, 10))
+	if clicks != 1 || b.State != Hot {
+		t.Fatalf("expected a click, got %d in state %v", clicks, b.State)
+	}
+	// releasing outside the button does not click it
+	env.Drag(image.Pt(10, 10), image.Pt(10, 50))
+	if clicks != 1 || b.State != Cold {
+		t.Fatalf("expected no click, got %d in state %v", clicks, b.State)
+	}
+}
+
+func TestTextField(t *testing.T) {
+	f := &TextField{}
+	b := &Button{}
+	root := &form{views: []ui.View{f, b}}
+	env := uitest.New(100, 100)
+	env.Start(root)
+	defer env.Close()
+
+	env.Click(image.Pt(10, 10))
+	if uitest.Focused(root) != f || f.State != Active {
+		t.Fatalf("expected the text field to have focus")
+	}
+	env.Type("hello")
+	env.Press("^a")
+	env.Type("<")
+	env.Press("^e")
+	env.Press(ui.Backspace)
+	if f.Text != "<hell" || f.Caret != [2]int{5, 5} {
+		t.Fatalf("unexpected text %q with caret %v", f.Text, f.Caret)
+	}
+	env.Press(ui.Key("$") + ui.Left)
+	if f.Caret != [2]int{5, 4} {
+		t.Fatalf("expected shift to extend the selection, got %v", f.Caret)
+	}
+	env.Click(image.Pt(10, 30))
+	if uitest.Focused(root) != b || f.State != Cold {
+		t.Fatalf("expected the text field to lose focus")
+	}
+}
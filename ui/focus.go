@@ -0,0 +1,142 @@
+package ui
+
+// Focusable is implemented by Components which can take the keyboard
+// focus when tabbing between views. Focusable reports whether the
+// Component currently accepts it, so that disabled views can be
+// skipped.
+//
+// Any view can still be focused by clicking on it, or by its Controller.
+type Focusable interface {
+	Component
+	Focusable() bool
+}
+
+// scope is a subtree which traps the focus, along with the Box which
+// had the focus before it.
+type scope struct {
+	box  *Box
+	prev *Box
+}
+
+// scope returns the subtree the focus is limited to.
+func (d *dispatcher) scope() *Box {
+	if len(d.scopes) == 0 {
+		return d.root
+	}
+	return d.scopes[len(d.scopes)-1].box
+}
+
+// focus moves the focus to b, or removes it if b is nil. The focus is
+// not moved out of the current scope.
+func (d *dispatcher) focus(b *Box) {
+	if b == d.keyFocus {
+		return
+	}
+	if b != nil && len(d.scopes) > 0 && !b.within(d.scope()) {
+		return
+	}
+	old := d.keyFocus
+	d.keyFocus = b
+	if old != nil {
+		old.focused = false
+		old.send(FocusLost{})
+	}
+	if b != nil && d.keyFocus == b {
+		b.focused = true
+		b.send(FocusGained{})
+	}
+}
+
+// tab moves the focus to the next Focusable in tree order within the
+// current scope, or the previous one if backward is set, wrapping
+// around at either end.
+func (d *dispatcher) tab(backward bool) {
+	var order []*Box
+	cur := -1
+	d.scope().walk(func(b *Box) {
+		if b.ctl == nil {
+			return
+		}
+		if f, ok := b.ctl.comp.(Focusable); ok && f.Focusable() {
+			if b == d.keyFocus {
+				cur = len(order)
+			}
+			order = append(order, b)
+		}
+	})
+	n := len(order)
+	if n == 0 {
+		return
+	}
+	next := 0
+	switch {
+	case cur < 0 && backward:
+		next = n - 1
+	case backward:
+		next = (cur + n - 1) % n
+	case cur >= 0:
+		next = (cur + 1) % n
+	}
+	d.focus(order[next])
+}
+
+// trap limits the focus to the subtree of b until it is released. If
+// the focus is outside of it, it moves to the first Focusable in it, or
+// to b itself if there is none. b must be within the current scope.
+func (d *dispatcher) trap(b *Box) {
+	if len(d.scopes) > 0 && !b.within(d.scope()) {
+		return
+	}
+	for _, s := range d.scopes {
+		if s.box == b {
+			return
+		}
+	}
+	d.scopes = append(d.scopes, scope{box: b, prev: d.keyFocus})
+	if d.keyFocus != nil && d.keyFocus.within(b) {
+		return
+	}
+	d.focus(nil)
+	d.tab(false)
+	if d.keyFocus == nil {
+		d.focus(b)
+	}
+}
+
+// release removes the scope of b, along with any scopes inside of it,
+// and gives the focus back to where it was before b trapped it.
+func (d *dispatcher) release(b *Box) {
+	for i, s := range d.scopes {
+		if s.box != b {
+			continue
+		}
+		d.scopes = d.scopes[:i]
+		if d.keyFocus == nil || d.keyFocus.within(b) {
+			d.focus(nil)
+			d.focus(s.prev)
+		}
+		return
+	}
+}
+
+// remove forgets b and its subviews before they are unmounted, taking
+// the focus away from them.
+func (d *dispatcher) remove(b *Box) {
+	for i := 0; i < len(d.scopes); i++ {
+		if s := d.scopes[i]; s.box.within(b) {
+			d.release(s.box)
+			break
+		}
+	}
+	if d.keyFocus != nil && d.keyFocus.within(b) {
+		d.focus(nil)
+	}
+	for i, s := range d.scopes {
+		if s.prev != nil && s.prev.within(b) {
+			d.scopes[i].prev = nil
+		}
+	}
+	if d.mouseFocus != nil && d.mouseFocus.within(b) {
+		d.mouseFocus = nil
+	}
+}
@@ -6,6 +6,7 @@ package glfwui
 import (
 	"image"
 	"runtime"
+	"time"
 
 	"j4k.co/exp/ui"
 
@@ -18,7 +19,8 @@ type Window struct {
 	waitc       chan struct{}
 	haslistened bool
 
-	mouse ui.MouseUpdate
+	mouse  ui.MouseState
+	clicks ui.ClickCounter
 }
 
 // Open opens a new OS window via GLFW.
@@ -62,10 +64,17 @@ func (w *Window) init() {
 	w.w.SetKeyCallback(w.onKeyPress)
 	w.w.SetMouseButtonCallback(w.onMouseButton)
 	w.w.SetCursorPositionCallback(w.onCursorPos)
+	w.w.SetScrollCallback(w.onScroll)
 	w.w.SetSizeCallback(w.onResize)
 	w.w.SetCloseCallback(w.onClose)
 }
 
+// SetClickInterval sets the longest time between the presses of a
+// double click, which is ui.DefaultClickInterval by default.
+func (w *Window) SetClickInterval(d time.Duration) {
+	w.clicks.Interval = d
+}
+
 func (w *Window) MakeContextCurrent() {
 	runtime.LockOSThread()
 	w.w.MakeContextCurrent()
@@ -125,18 +134,8 @@ func (w *Window) onCharPress(wnd *glfw3.Window, char uint) {
 }
 
 func (w *Window) onKeyPress(wnd *glfw3.Window, key glfw3.Key, scancode int, action glfw3.Action, mod glfw3.ModifierKey) {
-	var s []byte
-	if mod&glfw3.ModShift != 0 {
-		s = append(s, '$')
-	}
-	if mod&glfw3.ModControl != 0 {
-		s = append(s, '^')
-	}
-	if mod&glfw3.ModAlt != 0 {
-		s = append(s, '~')
-	}
-	// TODO: super?
-	s = translateKey(s, key)
+	w.mouse.Mod = ui.Key(mods(nil, mod))
+	s := translateKey(mods(nil, mod), key)
 	switch action {
 	case glfw3.Press:
 		w.dispatch(ui.KeyDown{
@@ -153,17 +152,58 @@ func (w *Window) onKeyPress(wnd *glfw3.Window, key glfw3.Key, scancode int, acti
 	}
 }
 
-func (w *Window) onMouseButton(wnd *glfw3.Window, btn glfw3.MouseButton, action glfw3.Action, mod glfw3.ModifierKey) {
-	switch btn {
-	case glfw3.MouseButton1:
-		w.mouse.Left = (action != glfw3.Release)
-		w.dispatch(w.mouse)
+// mods appends the modifier keys in mod to s, in the format of ui.Key.
+func mods(s []byte, mod glfw3.ModifierKey) []byte {
+	if mod&glfw3.ModShift != 0 {
+		s = append(s, '$')
+	}
+	if mod&glfw3.ModControl != 0 {
+		s = append(s, '^')
 	}
+	if mod&glfw3.ModAlt != 0 {
+		s = append(s, '~')
+	}
+	// TODO: super?
+	return s
+}
+
+func (w *Window) onMouseButton(wnd *glfw3.Window, btn glfw3.MouseButton, action glfw3.Action, mod glfw3.ModifierKey) {
+	// glfw numbers the buttons left, right, middle, and so on, as ui
+	// does
+	s := w.mouse
+	s.SetButton(ui.Button(btn-glfw3.MouseButton1), action != glfw3.Release)
+	s.Mod = ui.Key(mods(nil, mod))
+	w.updateMouse(s)
 }
 
 func (w *Window) onCursorPos(wnd *glfw3.Window, x, y float64) {
-	w.mouse.Point = image.Pt(int(x), int(y))
-	w.dispatch(w.mouse)
+	s := w.mouse
+	s.Point = image.Pt(int(x), int(y))
+	w.updateMouse(s)
+}
+
+func (w *Window) updateMouse(s ui.MouseState) {
+	m := ui.MouseUpdate{
+		MouseState: s,
+		Previous:   w.mouse,
+	}
+	m.Clicks = w.clicks.Count(m, eventTime())
+	w.mouse = s
+	w.dispatch(m)
+}
+
+// eventTime returns the time on the GLFW timer, which the events are
+// timed by, rather than the wall clock.
+func eventTime() time.Time {
+	return time.Unix(0, 0).Add(time.Duration(glfw3.GetTime() * float64(time.Second)))
+}
+
+func (w *Window) onScroll(wnd *glfw3.Window, xoff, yoff float64) {
+	w.dispatch(ui.Scroll{
+		MouseState: w.mouse,
+		DX:         xoff,
+		DY:         yoff,
+	})
 }
 
 func (w *Window) onResize(wnd *glfw3.Window, ww, h int) {
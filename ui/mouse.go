@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"image"
+	"time"
+)
+
+// Button identifies a mouse button.
+type Button int
+
+const (
+	ButtonLeft Button = iota
+	ButtonRight
+	ButtonMiddle
+
+	// NumButtons is the number of buttons a MouseState holds.
+	NumButtons = ButtonMiddle + 1 + 8
+)
+
+// Button reports whether button b is held down.
+func (s MouseState) Button(b Button) bool {
+	return s.buttons()&(1<<b) != 0
+}
+
+// SetButton sets whether button b is held down. Buttons past
+// NumButtons are ignored.
+func (s *MouseState) SetButton(b Button, down bool) {
+	switch {
+	case b == ButtonLeft:
+		s.Left = down
+	case b == ButtonRight:
+		s.Right = down
+	case b == ButtonMiddle:
+		s.Middle = down
+	case b > ButtonMiddle && b < NumButtons:
+		bit := uint8(1) << (b - ButtonMiddle - 1)
+		if down {
+			s.Extra |= bit
+		} else {
+			s.Extra &^= bit
+		}
+	}
+}
+
+// Pressed reports whether any button is held down.
+func (s MouseState) Pressed() bool {
+	return s.buttons() != 0
+}
+
+// buttons returns the buttons held down, with button b in bit b.
+func (s MouseState) buttons() uint16 {
+	var b uint16
+	if s.Left {
+		b |= 1 << ButtonLeft
+	}
+	if s.Right {
+		b |= 1 << ButtonRight
+	}
+	if s.Middle {
+		b |= 1 << ButtonMiddle
+	}
+	return b | uint16(s.Extra)<<(ButtonMiddle+1)
+}
+
+// DefaultClickInterval is the longest time between the presses of a
+// series of clicks, if not set in a ClickCounter.
+const DefaultClickInterval = 500 * time.Millisecond
+
+// clickSlop is how far, in either direction, the cursor may move
+// between the presses of a series of clicks.
+const clickSlop = 4
+
+// A ClickCounter counts the clicks of a series, for Environments to set
+// MouseUpdate.Clicks. Presses of the same button, each within Interval
+// and a few pixels of the one before, make up a series.
+type ClickCounter struct {
+	Interval time.Duration
+
+	n      int
+	button uint16
+	last   time.Time
+	pt     image.Point
+}
+
+// Count returns the click count of m, which happened at time t.
+func (c *ClickCounter) Count(m MouseUpdate, t time.Time) int {
+	now, prev := m.buttons(), m.Previous.buttons()
+	pressed := now &^ prev
+	if pressed == 0 {
+		if prev&^now != 0 {
+			return c.n
+		}
+		return 0
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultClickInterval
+	}
+	d := m.Point.Sub(c.pt)
+	if c.n > 0 && pressed == c.button && t.Sub(c.last) <= interval &&
+		abs(d.X) <= clickSlop && abs(d.Y) <= clickSlop {
+		c.n++
+	} else {
+		c.n = 1
+	}
+	c.button, c.last, c.pt = pressed, t, m.Point
+	return c.n
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,137 @@
+package ui_test
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"j4k.co/exp/ui"
+	"j4k.co/exp/ui/uitest"
+)
+
+func TestButtons(t *testing.T) {
+	var s ui.MouseState
+	for b := ui.ButtonLeft; b < ui.NumButtons; b++ {
+		s.SetButton(b, true)
+		if !s.Button(b) || !s.Pressed() {
+			t.Fatalf("expected button %d to be down", b)
+		}
+		s.SetButton(b, false)
+		if s.Pressed() {
+			t.Fatalf("expected button %d to be up", b)
+		}
+	}
+	s.SetButton(ui.ButtonMiddle, true)
+	if !s.Middle || s.Left || s.Right || s.Extra != 0 {
+		t.Fatalf("unexpected state %+v", s)
+	}
+	s.SetButton(ui.NumButtons, true)
+	if s.Button(ui.NumButtons) {
+		t.Fatalf("expected buttons past NumButtons to be ignored")
+	}
+}
+
+func TestClickCounter(t *testing.T) {
+	var c ui.ClickCounter
+	t0 := time.Unix(0, 0)
+	press := func(pt image.Point, b ui.Button, at time.Duration) (down, up int) {
+		var m ui.MouseUpdate
+		m.Point = pt
+		m.Previous.Point = pt
+		m.SetButton(b, true)
+		down = c.Count(m, t0.Add(at))
+		m.Previous, m.MouseState = m.MouseState, m.Previous
+		return down, c.Count(m, t0.Add(at))
+	}
+	steps := []struct {
+		pt       image.Point
+		b        ui.Button
+		at       time.Duration
+		expected int
+	}{
+		{image.Pt(10, 10), ui.ButtonLeft, 0, 1},
+		{image.Pt(11, 9), ui.ButtonLeft, 200 * time.Millisecond, 2},
+		{image.Pt(10, 10), ui.ButtonLeft, 400 * time.Millisecond, 3},
+		// too slow
+		{image.Pt(10, 10), ui.ButtonLeft, time.Second, 1},
+		// too far
+		{image.Pt(20, 10), ui.ButtonLeft, 1100 * time.Millisecond, 1},
+		// another button
+		{image.Pt(20, 10), ui.ButtonRight, 1200 * time.Millisecond, 1},
+		{image.Pt(20, 10), ui.ButtonRight, 1300 * time.Millisecond, 2},
+	}
+	for i, s := range steps {
+		down, up := press(s.pt, s.b, s.at)
+		if down != s.expected || up != s.expected {
+			t.Fatalf("step %d: expected %d clicks, got %d and %d", i, s.expected, down, up)
+		}
+	}
+	var m ui.MouseUpdate
+	m.Point = image.Pt(5, 5)
+	if n := c.Count(m, t0); n != 0 {
+		t.Fatalf("expected moves to count no clicks, got %d", n)
+	}
+	c.Interval = 2 * time.Second
+	press(image.Pt(0, 0), ui.ButtonLeft, 2*time.Second)
+	if down, _ := press(image.Pt(0, 0), ui.ButtonLeft, 3*time.Second); down != 2 {
+		t.Fatalf("expected Interval to be used, got %d clicks", down)
+	}
+}
+
+func TestDispatchMouse(t *testing.T) {
+	a, b := &eventChecker{}, &eventChecker{}
+	master := &mounter{views: []ui.View{a, b}}
+	env := uitest.New(100, 100)
+	env.Start(master)
+	defer env.Close()
+	a.SetBounds(image.Rect(0, 0, 50, 100))
+	b.SetBounds(image.Rect(50, 0, 100, 100))
+
+	// the view a button was pressed on keeps the mouse
+	env.Move(image.Pt(10, 10))
+	env.Hold("^")
+	env.ClickButton(image.Pt(10, 10), ui.ButtonRight)
+	down := env.Mouse()
+	down.SetButton(ui.ButtonMiddle, true)
+	env.Send(ui.MouseUpdate{MouseState: down, Previous: env.Mouse()})
+	moved := down
+	moved.Point = image.Pt(60, 10)
+	env.Send(ui.MouseUpdate{MouseState: moved, Previous: down})
+	if containsEvent(b.events, ui.MouseEnter{}) {
+		t.Fatalf("expected a to keep the mouse while the middle button is down")
+	}
+	released := moved
+	released.Middle = false
+	env.Send(ui.MouseUpdate{MouseState: released, Previous: moved})
+	if !containsEvent(b.events, ui.MouseEnter{}) {
+		t.Fatalf("expected b to get the mouse once the button is released")
+	}
+
+	a.events, b.events = nil, nil
+	env.Scroll(image.Pt(60, 10), 0, -1.5)
+	env.Scroll(image.Pt(10, 10), 0.5, 0)
+	var scrolls []ui.Scroll
+	for _, e := range b.events {
+		if s, ok := e.(ui.Scroll); ok {
+			scrolls = append(scrolls, s)
+		}
+	}
+	if len(scrolls) != 1 || scrolls[0].DY != -1.5 || scrolls[0].Point != image.Pt(60, 10) || !scrolls[0].Mod.Ctrl() {
+		t.Fatalf("unexpected scrolls %+v", scrolls)
+	}
+	if countEvents(a.events, ui.Scroll{}) != 1 {
+		t.Fatalf("expected scrolls to go to the view under the mouse")
+	}
+
+	a.events = nil
+	env.DoubleClick(image.Pt(10, 10))
+	var clicks []int
+	for _, e := range a.events {
+		if m, ok := e.(ui.MouseUpdate); ok && m.Clicks > 0 {
+			clicks = append(clicks, m.Clicks)
+		}
+	}
+	if len(clicks) != 4 || clicks[2] != 2 || clicks[3] != 2 {
+		t.Fatalf("expected a double click, got %v", clicks)
+	}
+}
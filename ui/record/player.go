@@ -0,0 +1,317 @@
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"j4k.co/exp/ui"
+	"j4k.co/exp/varintrle"
+)
+
+// A Player is a ui.Environment which plays back the events of a log
+// written by a Recorder, as fast as they are listened for.
+type Player struct {
+	r       *bufio.Reader
+	version byte
+	err     error
+	ratio   float32
+	size    ui.SizeUpdate
+	b       batch
+	i       int
+	next    [numCols]int
+	nkey    int
+	nscroll int
+	t       time.Duration
+}
+
+// NewPlayer returns a Player reading the log in r.
+func NewPlayer(r io.Reader) (*Player, error) {
+	p := &Player{
+		r: bufio.NewReader(r),
+	}
+	hdr := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(p.r, hdr); err != nil {
+		return nil, err
+	}
+	p.version = hdr[len(magic)]
+	if string(hdr[:len(magic)]) != magic || p.version < 1 || p.version > version {
+		return nil, errFormat
+	}
+	w, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return nil, err
+	}
+	h, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return nil, err
+	}
+	var ratio [4]byte
+	if _, err := io.ReadFull(p.r, ratio[:]); err != nil {
+		return nil, err
+	}
+	p.size = ui.SizeUpdate{Width: int(w), Height: int(h)}
+	p.ratio = math.Float32frombits(binary.LittleEndian.Uint32(ratio[:]))
+	return p, nil
+}
+
+// Size returns the size of the environment, as of the last event played
+// back.
+func (p *Player) Size() (width, height int, pixelRatio float32) {
+	return p.size.Width, p.size.Height, p.ratio
+}
+
+// Time returns the time, since the start of the recording, at which the
+// last event played back was recorded.
+func (p *Player) Time() time.Duration {
+	return p.t
+}
+
+// Listen returns the next event in the log.
+func (p *Player) Listen() (event interface{}, ok bool) {
+	if p.err != nil {
+		return nil, false
+	}
+	if p.i == p.b.n {
+		if p.err = p.readBatch(); p.err != nil {
+			return nil, false
+		}
+	}
+	event, p.err = p.event()
+	if p.err != nil {
+		return nil, false
+	}
+	p.i++
+	return event, true
+}
+
+// Err returns the error, if any, that ended the log early.
+func (p *Player) Err() error {
+	if p.err == io.EOF {
+		return nil
+	}
+	return p.err
+}
+
+// take returns the next value of column c.
+func (p *Player) take(c int) (int64, error) {
+	i := p.next[c]
+	if i >= len(p.b.cols[c]) {
+		return 0, varintrle.ErrCorrupt
+	}
+	p.next[c]++
+	return p.b.cols[c][i], nil
+}
+
+func (p *Player) event() (interface{}, error) {
+	var vals [numCols]int64
+	get := func(cols ...int) error {
+		for _, c := range cols {
+			v, err := p.take(c)
+			if err != nil {
+				return err
+			}
+			vals[c] = v
+		}
+		return nil
+	}
+	if err := get(colKind, colTime); err != nil {
+		return nil, err
+	}
+	p.t = time.Duration(vals[colTime]) * time.Microsecond
+	switch vals[colKind] {
+	case kindKeyDown, kindKeyUp, kindKeyRepeat:
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		switch vals[colKind] {
+		case kindKeyDown:
+			return ui.KeyDown{Key: key}, nil
+		case kindKeyUp:
+			return ui.KeyUp{Key: key}, nil
+		}
+		return ui.KeyRepeat{Key: key}, nil
+	case kindUnicode:
+		err := get(colChar)
+		return ui.UnicodeTyped{C: rune(vals[colChar])}, err
+	case kindMouse:
+		s, err := p.mouseState()
+		if err != nil {
+			return nil, err
+		}
+		if err := get(colPrevX, colPrevY, colPrevButtons); err != nil {
+			return nil, err
+		}
+		m := ui.MouseUpdate{
+			MouseState: s,
+			Previous:   mouseState(vals[colPrevX], vals[colPrevY], vals[colPrevButtons]),
+		}
+		if p.version > 1 {
+			err = get(colPrevMod, colClicks)
+			m.Previous.Mod = modKey(vals[colPrevMod])
+			m.Clicks = int(vals[colClicks])
+		}
+		return m, err
+	case kindScroll:
+		s, err := p.mouseState()
+		if err != nil {
+			return nil, err
+		}
+		if p.nscroll >= len(p.b.scroll[0]) {
+			return nil, varintrle.ErrCorrupt
+		}
+		e := ui.Scroll{
+			MouseState: s,
+			DX:         p.b.scroll[0][p.nscroll],
+			DY:         p.b.scroll[1][p.nscroll],
+		}
+		p.nscroll++
+		return e, nil
+	case kindSize:
+		err := get(colWidth, colHeight)
+		p.size = ui.SizeUpdate{Width: int(vals[colWidth]), Height: int(vals[colHeight])}
+		return p.size, err
+	}
+	return nil, varintrle.ErrCorrupt
+}
+
+// key returns the next key.
+func (p *Player) key() (ui.Key, error) {
+	if p.nkey >= len(p.b.keys) {
+		return "", varintrle.ErrCorrupt
+	}
+	p.nkey++
+	return ui.Key(p.b.keys[p.nkey-1]), nil
+}
+
+// mouseState returns the next mouse position, buttons and modifiers.
+func (p *Player) mouseState() (s ui.MouseState, err error) {
+	var vals [3]int64
+	for i, c := range []int{colX, colY, colButtons} {
+		if vals[i], err = p.take(c); err != nil {
+			return s, err
+		}
+	}
+	s = mouseState(vals[0], vals[1], vals[2])
+	if p.version == 1 {
+		return s, nil
+	}
+	mod, err := p.take(colMod)
+	s.Mod = modKey(mod)
+	return s, err
+}
+
+// modKey returns the modifier keys in mod, in the format of ui.Key.
+func modKey(mod int64) ui.Key {
+	var k []byte
+	if mod&modShift != 0 {
+		k = append(k, '$')
+	}
+	if mod&modCtrl != 0 {
+		k = append(k, '^')
+	}
+	if mod&modAlt != 0 {
+		k = append(k, '~')
+	}
+	return ui.Key(k)
+}
+
+func mouseState(x, y, buttons int64) ui.MouseState {
+	var s ui.MouseState
+	s.X, s.Y = int(x), int(y)
+	for i := ui.ButtonLeft; i < ui.NumButtons; i++ {
+		s.SetButton(i, buttons&(1<<i) != 0)
+	}
+	return s
+}
+
+func (p *Player) readBatch() error {
+	n, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return err
+	}
+	if n > maxBatch {
+		return varintrle.ErrCorrupt
+	}
+	p.b.reset()
+	p.b.n = int(n)
+	p.i = 0
+	p.nkey = 0
+	p.nscroll = 0
+	p.next = [numCols]int{}
+	ncols := numCols
+	if p.version == 1 {
+		ncols = numCols1
+	}
+	var buf []byte
+	for c := range p.b.cols[:ncols] {
+		if buf, err = p.readBytes(buf); err != nil {
+			return err
+		}
+		p.b.cols[c], err = varintrle.DecodeStrict(p.b.cols[c], buf, int(n))
+		if err != nil {
+			return err
+		}
+	}
+	if p.version > 1 {
+		for i := range p.b.scroll {
+			if buf, err = p.readBytes(buf); err != nil {
+				return err
+			}
+			p.b.scroll[i], err = varintrle.DecodeFloats(p.b.scroll[i], buf)
+			if err != nil {
+				return err
+			}
+			if uint64(len(p.b.scroll[i])) > n {
+				return varintrle.ErrCorrupt
+			}
+		}
+		if len(p.b.scroll[0]) != len(p.b.scroll[1]) {
+			return varintrle.ErrCorrupt
+		}
+	}
+	nkeys, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return unexpected(err)
+	}
+	if nkeys > n {
+		return varintrle.ErrCorrupt
+	}
+	for ; nkeys > 0; nkeys-- {
+		if buf, err = p.readBytes(buf); err != nil {
+			return err
+		}
+		p.b.keys = append(p.b.keys, string(buf))
+	}
+	if len(p.b.cols[colKind]) != p.b.n {
+		return varintrle.ErrCorrupt
+	}
+	return nil
+}
+
+// readBytes reads a uvarint length followed by that many bytes.
+func (p *Player) readBytes(buf []byte) ([]byte, error) {
+	size, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return buf, unexpected(err)
+	}
+	if size > 1<<24 {
+		return buf, varintrle.ErrCorrupt
+	}
+	if uint64(cap(buf)) < size {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+	_, err = io.ReadFull(p.r, buf)
+	return buf, unexpected(err)
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
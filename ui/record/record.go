@@ -0,0 +1,122 @@
+// Package record records the events of a ui.Environment to a compact
+// log, and plays them back, so that a session can be replayed event
+// for event.
+//
+// A log starts with a header:
+//
+//	magic    "uirc"
+//	version  2
+//	size     uvarint width and height, and the pixel ratio as a
+//	         float32, 4 bytes little endian
+//
+// followed by batches of events, each holding
+//
+//	n        uvarint number of events
+//	columns  each a uvarint size followed by a varintrle stream
+//	scroll   the dx and dy columns, each a uvarint size followed by a
+//	         varintrle float stream
+//	keys     uvarint number of keys, then each key as a uvarint length
+//	         followed by its text
+//
+// Every event has a kind and a time, in microseconds since the start of
+// the recording. The other columns only hold values for the events
+// that have the field, in order, so that for instance the x column
+// holds the mouse positions one after another. Numeric columns are
+// delta encoded, which suits mouse positions and times well, as they
+// tend to change a little at a time. Key events hold their key in the
+// keys.
+//
+// Version 1 logs lack the clicks and modifier columns and scroll
+// events, and are played back without them.
+package record
+
+import (
+	"errors"
+	"time"
+
+	"j4k.co/exp/varintrle"
+)
+
+const (
+	magic   = "uirc"
+	version = 2
+
+	// batchSize is the number of events a Recorder holds before writing
+	// them out.
+	batchSize = 1024
+
+	// maxBatch is the most events a Player reads in one batch, which
+	// leaves room for batches larger than a Recorder writes, but keeps
+	// a damaged count from making it decode without limit.
+	maxBatch = 4 * batchSize
+
+	// flushInterval is the longest a Recorder holds events before
+	// writing them out, so that little is lost if the program dies.
+	flushInterval = time.Second
+)
+
+var errFormat = errors.New("record: not a ui event log")
+
+// event kinds
+const (
+	kindKeyDown = iota
+	kindKeyUp
+	kindKeyRepeat
+	kindUnicode
+	kindMouse
+	kindSize
+	kindScroll
+)
+
+// columns of a batch
+const (
+	colKind = iota
+	colTime
+	colX
+	colY
+	colButtons
+	colPrevX
+	colPrevY
+	colPrevButtons
+	colWidth
+	colHeight
+	colChar
+	colClicks
+	colMod
+	colPrevMod
+	numCols
+)
+
+// modifier keys, in the modifier column
+const (
+	modShift = 1 << iota
+	modCtrl
+	modAlt
+)
+
+// version 1 logs end their columns before colClicks
+const numCols1 = colClicks
+
+var (
+	kindOptions   = varintrle.Options{Version: 2}
+	columnOptions = varintrle.Options{Transform: varintrle.Delta, Version: 2}
+)
+
+// batch holds the columns of a batch of events.
+type batch struct {
+	n      int
+	cols   [numCols][]int64
+	scroll [2][]float64
+	keys   []string
+}
+
+func (b *batch) reset() {
+	b.n = 0
+	for i := range b.cols {
+		b.cols[i] = b.cols[i][:0]
+	}
+	for i := range b.scroll {
+		b.scroll[i] = b.scroll[i][:0]
+	}
+	b.keys = b.keys[:0]
+}
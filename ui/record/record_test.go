@@ -0,0 +1,242 @@
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"reflect"
+	"testing"
+	"time"
+
+	"j4k.co/exp/ui"
+	"j4k.co/exp/varintrle"
+)
+
+type env struct {
+	events []interface{}
+}
+
+func (e *env) Size() (w, h int, pixelRatio float32) {
+	return 640, 480, 2
+}
+
+func (e *env) Listen() (event interface{}, ok bool) {
+	if len(e.events) == 0 {
+		return nil, false
+	}
+	event = e.events[0]
+	e.events = e.events[1:]
+	return event, true
+}
+
+func TestRecordPlay(t *testing.T) {
+	var events []interface{}
+	prev := ui.MouseState{}
+	for i := 0; i < 3000; i++ {
+		m := ui.MouseUpdate{Previous: prev}
+		m.Point = image.Pt(100+i%50, 200-i%30)
+		m.Left = i%100 < 10
+		m.Middle = i%300 >= 50 && i%300 < 55
+		m.SetButton(ui.ButtonMiddle+3, i%700 == 5)
+		if i%400 < 20 {
+			m.Mod = "$^"
+		}
+		if m.Left != prev.Left {
+			m.Clicks = 1
+		}
+		events = append(events, m)
+		prev = m.MouseState
+		switch i % 500 {
+		case 1:
+			events = append(events, ui.KeyDown{Key: "^f"}, ui.KeyRepeat{Key: "^f"}, ui.KeyUp{Key: "^f"})
+		case 2:
+			events = append(events, ui.UnicodeTyped{C: 'é'})
+		case 3:
+			events = append(events, ui.SizeUpdate{Width: 800 + i, Height: 600})
+		case 4, 5, 6:
+			events = append(events, ui.Scroll{MouseState: m.MouseState, DX: 0.25, DY: -0.1 * float64(i%7)})
+		}
+	}
+	// events come quickly enough to fill whole batches before a second
+	// has passed
+	const step = time.Millisecond
+	buf := &bytes.Buffer{}
+	r := NewRecorder(&env{events: append([]interface{}(nil), events...)}, buf)
+	clock := time.Unix(0, 0)
+	r.start, r.flushed = clock, clock
+	r.now = func() time.Time {
+		clock = clock.Add(step)
+		return clock
+	}
+	var passed []interface{}
+	for {
+		e, ok := r.Listen()
+		if !ok {
+			break
+		}
+		passed = append(passed, e)
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if !reflect.DeepEqual(events, passed) {
+		t.Fatalf("Recorder did not pass on the events")
+	}
+	if buf.Len() > len(events)*3 {
+		t.Fatalf("expected a compact log, got %d bytes for %d events", buf.Len(), len(events))
+	}
+
+	p, err := NewPlayer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h, ratio := p.Size(); w != 640 || h != 480 || ratio != 2 {
+		t.Fatalf("unexpected size %d, %d, %v", w, h, ratio)
+	}
+	var played []interface{}
+	for {
+		e, ok := p.Listen()
+		if !ok {
+			break
+		}
+		played = append(played, e)
+		if p.Time() != time.Duration(len(played))*step {
+			t.Fatalf("event %d played at %v", len(played), p.Time())
+		}
+	}
+	if p.Err() != nil {
+		t.Fatal(p.Err())
+	}
+	if !reflect.DeepEqual(events, played) {
+		t.Fatalf("Player did not play back the events")
+	}
+
+	p, err = NewPlayer(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, ok := p.Listen(); !ok {
+			break
+		}
+	}
+	if p.Err() == nil {
+		t.Fatalf("expected an error for a truncated log")
+	}
+}
+
+// writer counts the writes made to it.
+type writer struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestRecorderFlush(t *testing.T) {
+	var events []interface{}
+	for i := 0; i < 100; i++ {
+		events = append(events, ui.UnicodeTyped{C: 'a'})
+	}
+	w := &writer{}
+	r := NewRecorder(&env{events: events}, w)
+	if w.writes != 1 || !bytes.HasPrefix(w.Bytes(), []byte(magic)) {
+		t.Fatalf("expected the header to be written right away")
+	}
+	clock := time.Unix(0, 0)
+	r.start, r.flushed = clock, clock
+	r.now = func() time.Time {
+		clock = clock.Add(100 * time.Millisecond)
+		return clock
+	}
+	for i := 0; i < 10; i++ {
+		r.Listen()
+	}
+	if w.writes != 2 {
+		t.Fatalf("expected the events to be written after a second, got %d writes", w.writes)
+	}
+	for {
+		if _, ok := r.Listen(); !ok {
+			break
+		}
+	}
+	if r.Err() != nil || w.writes != 11 {
+		t.Fatalf("expected a write every second, got %d writes: %v", w.writes, r.Err())
+	}
+}
+
+func TestPlayVersion1(t *testing.T) {
+	var b batch
+	b.n = 2
+	b.cols[colKind] = []int64{kindMouse, kindKeyDown}
+	b.cols[colTime] = []int64{10, 20}
+	b.cols[colX] = []int64{5}
+	b.cols[colY] = []int64{6}
+	b.cols[colButtons] = []int64{3}
+	b.cols[colPrevX] = []int64{4}
+	b.cols[colPrevY] = []int64{6}
+	b.cols[colPrevButtons] = []int64{1}
+	b.keys = []string{"^f"}
+	log := append([]byte(magic), 1, 10, 10, 0, 0, 0x80, 0x3f)
+	log = binary.AppendUvarint(log, uint64(b.n))
+	for i, vals := range b.cols[:numCols1] {
+		opt := columnOptions
+		if i == colKind {
+			opt = kindOptions
+		}
+		col, err := varintrle.AppendRunOptions(nil, vals, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log = binary.AppendUvarint(log, uint64(len(col)))
+		log = append(log, col...)
+	}
+	log = append(log, 1, 2, '^', 'f')
+
+	p, err := NewPlayer(bytes.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var played []interface{}
+	for {
+		e, ok := p.Listen()
+		if !ok {
+			break
+		}
+		played = append(played, e)
+	}
+	if p.Err() != nil {
+		t.Fatal(p.Err())
+	}
+	expected := []interface{}{
+		ui.MouseUpdate{
+			MouseState: ui.MouseState{Point: image.Pt(5, 6), Left: true, Right: true},
+			Previous:   ui.MouseState{Point: image.Pt(4, 6), Left: true},
+		},
+		ui.KeyDown{Key: "^f"},
+	}
+	if !reflect.DeepEqual(expected, played) {
+		t.Fatalf("expected %v, got %v", expected, played)
+	}
+}
+
+func TestPlayHugeBatch(t *testing.T) {
+	// a batch claiming a great many events, with a column that would
+	// hold them all in a few bytes
+	col := binary.AppendUvarint([]byte{1, 0, 0x10, 0x80}, 1<<34)
+	log := append([]byte(magic), 1, 10, 10, 0, 0, 0x80, 0x3f)
+	log = binary.AppendUvarint(log, 1<<34)
+	log = binary.AppendUvarint(log, uint64(len(col)))
+	log = append(log, col...)
+
+	p, err := NewPlayer(bytes.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Listen(); ok || p.Err() != varintrle.ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt, got %v", p.Err())
+	}
+}
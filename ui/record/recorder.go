@@ -0,0 +1,195 @@
+package record
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"j4k.co/exp/ui"
+	"j4k.co/exp/varintrle"
+)
+
+// A Recorder is a ui.Environment which passes on the events of another
+// Environment, writing each of them to a log as it goes. Events of
+// kinds the log has no room for are passed on, but not recorded.
+type Recorder struct {
+	env   ui.Environment
+	w     io.Writer
+	err   error
+	start time.Time
+	b     batch
+	buf   []byte
+
+	// when the log was last written to
+	flushed time.Time
+
+	// now returns the current time, and is replaced in tests
+	now func() time.Time
+}
+
+// NewRecorder returns a Recorder passing on the events of env, and
+// logging them to w. The header of the log is written to w right away,
+// and any error doing so is returned by Err.
+func NewRecorder(env ui.Environment, w io.Writer) *Recorder {
+	r := &Recorder{
+		env: env,
+		w:   w,
+		now: time.Now,
+	}
+	r.start = r.now()
+	w0, h0, ratio := env.Size()
+	r.buf = append(r.buf, magic...)
+	r.buf = append(r.buf, version)
+	r.buf = binary.AppendUvarint(r.buf, uint64(w0))
+	r.buf = binary.AppendUvarint(r.buf, uint64(h0))
+	r.buf = binary.LittleEndian.AppendUint32(r.buf, math.Float32bits(ratio))
+	r.flushed = r.start
+	r.Flush()
+	return r
+}
+
+// Size returns the size of the underlying Environment.
+func (r *Recorder) Size() (width, height int, pixelRatio float32) {
+	return r.env.Size()
+}
+
+// Listen returns the next event of the underlying Environment, after
+// recording it. The log is flushed when a batch of events is full, when
+// an event comes a second or more after the last flush, and once there
+// are no more events.
+func (r *Recorder) Listen() (event interface{}, ok bool) {
+	event, ok = r.env.Listen()
+	if !ok {
+		r.Flush()
+		return nil, false
+	}
+	t := r.now()
+	r.add(event, t)
+	if r.b.n >= batchSize || t.Sub(r.flushed) >= flushInterval {
+		r.Flush()
+		r.flushed = t
+	}
+	return event, true
+}
+
+func (r *Recorder) add(event interface{}, t time.Time) {
+	b := &r.b
+	var kind int64
+	switch e := event.(type) {
+	case ui.KeyDown:
+		kind = kindKeyDown
+		b.keys = append(b.keys, string(e.Key))
+	case ui.KeyUp:
+		kind = kindKeyUp
+		b.keys = append(b.keys, string(e.Key))
+	case ui.KeyRepeat:
+		kind = kindKeyRepeat
+		b.keys = append(b.keys, string(e.Key))
+	case ui.UnicodeTyped:
+		kind = kindUnicode
+		b.cols[colChar] = append(b.cols[colChar], int64(e.C))
+	case ui.MouseUpdate:
+		kind = kindMouse
+		b.addMouse(e.MouseState)
+		b.cols[colPrevX] = append(b.cols[colPrevX], int64(e.Previous.X))
+		b.cols[colPrevY] = append(b.cols[colPrevY], int64(e.Previous.Y))
+		b.cols[colPrevButtons] = append(b.cols[colPrevButtons], buttons(e.Previous))
+		b.cols[colPrevMod] = append(b.cols[colPrevMod], mods(e.Previous.Mod))
+		b.cols[colClicks] = append(b.cols[colClicks], int64(e.Clicks))
+	case ui.Scroll:
+		kind = kindScroll
+		b.addMouse(e.MouseState)
+		b.scroll[0] = append(b.scroll[0], e.DX)
+		b.scroll[1] = append(b.scroll[1], e.DY)
+	case ui.SizeUpdate:
+		kind = kindSize
+		b.cols[colWidth] = append(b.cols[colWidth], int64(e.Width))
+		b.cols[colHeight] = append(b.cols[colHeight], int64(e.Height))
+	default:
+		return
+	}
+	b.n++
+	b.cols[colKind] = append(b.cols[colKind], kind)
+	b.cols[colTime] = append(b.cols[colTime], int64(t.Sub(r.start)/time.Microsecond))
+}
+
+// addMouse adds the position, buttons and modifiers of s.
+func (b *batch) addMouse(s ui.MouseState) {
+	b.cols[colX] = append(b.cols[colX], int64(s.X))
+	b.cols[colY] = append(b.cols[colY], int64(s.Y))
+	b.cols[colButtons] = append(b.cols[colButtons], buttons(s))
+	b.cols[colMod] = append(b.cols[colMod], mods(s.Mod))
+}
+
+func mods(k ui.Key) int64 {
+	var mod int64
+	if k.Shift() {
+		mod |= modShift
+	}
+	if k.Ctrl() {
+		mod |= modCtrl
+	}
+	if k.Alt() {
+		mod |= modAlt
+	}
+	return mod
+}
+
+// buttons returns the buttons held down in s, with button i in bit i.
+func buttons(s ui.MouseState) int64 {
+	var b int64
+	for i := ui.ButtonLeft; i < ui.NumButtons; i++ {
+		if s.Button(i) {
+			b |= 1 << i
+		}
+	}
+	return b
+}
+
+// Flush writes out the events recorded so far.
+func (r *Recorder) Flush() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.b.n > 0 {
+		r.buf = appendBatch(r.buf, &r.b)
+		r.b.reset()
+	}
+	if len(r.buf) > 0 {
+		_, r.err = r.w.Write(r.buf)
+		r.buf = r.buf[:0]
+	}
+	return r.err
+}
+
+// Err returns the first error met while writing the log.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func appendBatch(dst []byte, b *batch) []byte {
+	dst = binary.AppendUvarint(dst, uint64(b.n))
+	var col []byte
+	for i, vals := range b.cols {
+		opt := columnOptions
+		if i == colKind {
+			opt = kindOptions
+		}
+		// the options of the columns are always valid
+		col, _ = varintrle.AppendRunOptions(col[:0], vals, opt)
+		dst = binary.AppendUvarint(dst, uint64(len(col)))
+		dst = append(dst, col...)
+	}
+	for _, vals := range b.scroll {
+		col = varintrle.AppendFloats(col[:0], vals)
+		dst = binary.AppendUvarint(dst, uint64(len(col)))
+		dst = append(dst, col...)
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(b.keys)))
+	for _, k := range b.keys {
+		dst = binary.AppendUvarint(dst, uint64(len(k)))
+		dst = append(dst, k...)
+	}
+	return dst
+}
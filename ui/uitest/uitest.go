@@ -0,0 +1,212 @@
+// Package uitest provides a scriptable ui.Environment, and helpers for
+// looking through a tree of views, for testing components without a
+// window.
+package uitest
+
+import (
+	"image"
+	"time"
+
+	"j4k.co/exp/ui"
+)
+
+// An Environment is a ui.Environment driven by calls to its methods,
+// which each send one or more events and return once the events have
+// been dispatched, so that tests can check their results right away.
+type Environment struct {
+	width, height int
+	ratio         float32
+	mouse         ui.MouseState
+	clicks        ui.ClickCounter
+	now           time.Time
+
+	eventc  chan interface{}
+	waitc   chan struct{}
+	donec   chan error
+	started bool
+	closed  bool
+}
+
+// dragSteps is the number of moves Drag makes between its end points.
+const dragSteps = 4
+
+// New returns a new Environment of the given size.
+func New(width, height int) *Environment {
+	return &Environment{
+		width:  width,
+		height: height,
+		ratio:  1,
+		now:    time.Unix(0, 0),
+		eventc: make(chan interface{}),
+		waitc:  make(chan struct{}),
+		donec:  make(chan error, 1),
+	}
+}
+
+// Start dispatches the events of e to master, as ui.Dispatch does, and
+// returns once master is mounted.
+func (e *Environment) Start(master ui.Component) {
+	if e.closed {
+		panic("uitest: environment closed")
+	}
+	if e.started {
+		panic("uitest: environment already started")
+	}
+	e.started = true
+	go func() {
+		e.donec <- ui.Dispatch(e, master)
+	}()
+	<-e.waitc
+}
+
+// Close ends the events of e, which unmounts the views, and returns the
+// error returned by ui.Dispatch. Closing an environment which was never
+// started, or is already closed, does nothing.
+func (e *Environment) Close() error {
+	if !e.started || e.closed {
+		e.closed = true
+		return nil
+	}
+	e.closed = true
+	close(e.eventc)
+	return <-e.donec
+}
+
+// Size returns the size of the environment.
+func (e *Environment) Size() (width, height int, pixelRatio float32) {
+	return e.width, e.height, e.ratio
+}
+
+// Listen waits for the next event sent to the environment.
+func (e *Environment) Listen() (event interface{}, ok bool) {
+	e.waitc <- struct{}{}
+	event, ok = <-e.eventc
+	return event, ok
+}
+
+// Send sends event, and returns once it has been dispatched.
+func (e *Environment) Send(event interface{}) {
+	if e.closed {
+		panic("uitest: environment closed")
+	}
+	if !e.started {
+		panic("uitest: environment not started")
+	}
+	e.eventc <- event
+	<-e.waitc
+}
+
+// Now returns the time on the virtual clock of the environment, which
+// only moves forward when Advance is called. The clock times the
+// clicks the environment counts, and components which read the time
+// through a function can be given Now in place of time.Now.
+func (e *Environment) Now() time.Time {
+	return e.now
+}
+
+// Advance moves the virtual clock forward by d.
+func (e *Environment) Advance(d time.Duration) {
+	e.now = e.now.Add(d)
+}
+
+// Mouse returns the current state of the mouse.
+func (e *Environment) Mouse() ui.MouseState {
+	return e.mouse
+}
+
+func (e *Environment) setMouse(s ui.MouseState) {
+	m := ui.MouseUpdate{
+		MouseState: s,
+		Previous:   e.mouse,
+	}
+	m.Clicks = e.clicks.Count(m, e.now)
+	e.mouse = s
+	e.Send(m)
+}
+
+// Hold sets the modifier keys, in the format of ui.Key, that later
+// mouse events hold down, eg. "$" for Shift. It sends no events.
+func (e *Environment) Hold(mod ui.Key) {
+	e.mouse.Mod = mod
+}
+
+// SetClickInterval sets the longest time on the virtual clock between
+// the presses of a double click, which is ui.DefaultClickInterval by
+// default.
+func (e *Environment) SetClickInterval(d time.Duration) {
+	e.clicks.Interval = d
+}
+
+// Move moves the mouse to pt.
+func (e *Environment) Move(pt image.Point) {
+	s := e.mouse
+	s.Point = pt
+	e.setMouse(s)
+}
+
+// Click moves the mouse to pt, and presses and releases the left
+// button.
+func (e *Environment) Click(pt image.Point) {
+	e.ClickButton(pt, ui.ButtonLeft)
+}
+
+// DoubleClick clicks twice at pt, without advancing the clock.
+func (e *Environment) DoubleClick(pt image.Point) {
+	e.Click(pt)
+	e.Click(pt)
+}
+
+// ClickButton moves the mouse to pt, and presses and releases button b.
+func (e *Environment) ClickButton(pt image.Point, b ui.Button) {
+	e.Move(pt)
+	e.setButton(b, true)
+	e.setButton(b, false)
+}
+
+// Drag moves the mouse to from, presses the left button, moves the
+// mouse in a few steps to to, and releases the button.
+func (e *Environment) Drag(from, to image.Point) {
+	e.Move(from)
+	e.setButton(ui.ButtonLeft, true)
+	d := to.Sub(from)
+	for i := 1; i <= dragSteps; i++ {
+		e.Move(from.Add(d.Mul(i).Div(dragSteps)))
+	}
+	e.setButton(ui.ButtonLeft, false)
+}
+
+func (e *Environment) setButton(b ui.Button, down bool) {
+	s := e.mouse
+	s.SetButton(b, down)
+	e.setMouse(s)
+}
+
+// Scroll moves the mouse to pt, and scrolls by dx and dy.
+func (e *Environment) Scroll(pt image.Point, dx, dy float64) {
+	e.Move(pt)
+	e.Send(ui.Scroll{
+		MouseState: e.mouse,
+		DX:         dx,
+		DY:         dy,
+	})
+}
+
+// Type sends a ui.UnicodeTyped event for each character of text.
+func (e *Environment) Type(text string) {
+	for _, c := range text {
+		e.Send(ui.UnicodeTyped{C: c})
+	}
+}
+
+// Press presses and releases key, which is in the format of ui.Key,
+// such as "^a" for Control + A.
+func (e *Environment) Press(key ui.Key) {
+	e.Send(ui.KeyDown{Key: key})
+	e.Send(ui.KeyUp{Key: key})
+}
+
+// Resize changes the size of the environment.
+func (e *Environment) Resize(width, height int) {
+	e.width, e.height = width, height
+	e.Send(ui.SizeUpdate{Width: width, Height: height})
+}
@@ -0,0 +1,135 @@
+package uitest
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"j4k.co/exp/ui"
+)
+
+type recorder struct {
+	ui.Box
+	events []interface{}
+}
+
+func (r *recorder) Receive(ctl *ui.Controller, event interface{}) {
+	r.events = append(r.events, event)
+}
+
+func TestEnvironment(t *testing.T) {
+	env := New(100, 80)
+	r := &recorder{}
+	// events bubble up to the master, so watch a view below it
+	root := &parent{kids: []ui.View{r}}
+	env.Start(root)
+	r.SetBounds(image.Rect(0, 0, 100, 80))
+	env.Drag(image.Pt(0, 0), image.Pt(40, 20))
+	var moves []ui.MouseUpdate
+	for _, e := range r.events {
+		if m, ok := e.(ui.MouseUpdate); ok {
+			moves = append(moves, m)
+		}
+	}
+	if len(moves) != dragSteps+3 {
+		t.Fatalf("expected %d mouse updates, got %d", dragSteps+3, len(moves))
+	}
+	last := moves[len(moves)-1]
+	if last.Point != image.Pt(40, 20) || last.Left || !last.Previous.Left {
+		t.Fatalf("unexpected last mouse update %+v", last)
+	}
+	for i, m := range moves[1:] {
+		if m.Previous != moves[i].MouseState {
+			t.Fatalf("mouse update %d has the wrong previous state", i+1)
+		}
+	}
+
+	env.Type("hé")
+	env.Press("^a")
+	n := len(r.events)
+	if r.events[n-4] != (ui.UnicodeTyped{C: 'h'}) || r.events[n-3] != (ui.UnicodeTyped{C: 'é'}) ||
+		r.events[n-2] != (ui.KeyDown{Key: "^a"}) || r.events[n-1] != (ui.KeyUp{Key: "^a"}) {
+		t.Fatalf("unexpected events %v", r.events[n-4:])
+	}
+
+	t0 := env.Now()
+	env.DoubleClick(image.Pt(10, 10))
+	if c := lastPress(r.events).Clicks; c != 2 {
+		t.Fatalf("expected a double click, got %d clicks", c)
+	}
+	env.Advance(time.Second)
+	if env.Now().Sub(t0) != time.Second {
+		t.Fatalf("clock did not advance")
+	}
+	env.Click(image.Pt(10, 10))
+	if c := lastPress(r.events).Clicks; c != 1 {
+		t.Fatalf("expected the clock to end the double click, got %d clicks", c)
+	}
+
+	if err := env.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.events[len(r.events)-1].(ui.Unmount); !ok {
+		t.Fatalf("expected the view to be unmounted")
+	}
+	if err := env.Close(); err != nil {
+		t.Fatalf("expected a second Close to do nothing, got %v", err)
+	}
+	if err := New(10, 10).Close(); err != nil {
+		t.Fatalf("expected Close before Start to do nothing, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected Send after Close to panic")
+		}
+	}()
+	env.Press("a")
+}
+
+// lastPress returns the last mouse update in events which pressed the
+// left button.
+func lastPress(events []interface{}) ui.MouseUpdate {
+	for i := len(events) - 1; i >= 0; i-- {
+		if m, ok := events[i].(ui.MouseUpdate); ok && m.Left && !m.Previous.Left {
+			return m
+		}
+	}
+	return ui.MouseUpdate{}
+}
+
+type parent struct {
+	ui.Box
+	kids []ui.View
+}
+
+func (p *parent) Receive(ctl *ui.Controller, event interface{}) {
+	if _, ok := event.(ui.Mount); ok {
+		ctl.Mount(p.kids...)
+	}
+}
+
+func TestViews(t *testing.T) {
+	a, b := &recorder{}, &recorder{}
+	inner := &parent{kids: []ui.View{b}}
+	root := &parent{kids: []ui.View{a, inner}}
+	env := New(100, 100)
+	env.Start(root)
+	defer env.Close()
+	a.SetBounds(image.Rect(0, 0, 10, 10))
+	inner.SetBounds(image.Rect(50, 50, 100, 100))
+	b.SetBounds(image.Rect(60, 60, 70, 70))
+
+	if all := FindAll[*recorder](root); len(all) != 2 || all[0] != a || all[1] != b {
+		t.Fatalf("FindAll found %v", all)
+	}
+	if p, ok := Find[*parent](inner); !ok || p != inner {
+		t.Fatalf("Find did not find the inner view")
+	}
+	if At(root, image.Pt(65, 65)) != b || At(root, image.Pt(55, 55)) != inner || At(root, image.Pt(200, 5)) != nil {
+		t.Fatalf("At found the wrong views")
+	}
+	env.Click(image.Pt(65, 65))
+	if Focused(root) != b {
+		t.Fatalf("expected b to have focus")
+	}
+}
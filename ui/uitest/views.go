@@ -0,0 +1,75 @@
+package uitest
+
+import (
+	"image"
+
+	"j4k.co/exp/ui"
+)
+
+// Walk calls fn for v and each of its subviews, parents before their
+// children, until fn returns false.
+func Walk(v ui.View, fn func(ui.View) bool) bool {
+	if !fn(v) {
+		return false
+	}
+	for i := 0; i < v.Subviews(); i++ {
+		if !Walk(v.Sub(i), fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first view of type T in the tree rooted at v.
+func Find[T ui.View](v ui.View) (found T, ok bool) {
+	Walk(v, func(v ui.View) bool {
+		found, ok = v.(T)
+		return !ok
+	})
+	return found, ok
+}
+
+// FindAll returns all views of type T in the tree rooted at v.
+func FindAll[T ui.View](v ui.View) []T {
+	var all []T
+	Walk(v, func(v ui.View) bool {
+		if t, ok := v.(T); ok {
+			all = append(all, t)
+		}
+		return true
+	})
+	return all
+}
+
+// At returns the deepest view in the tree rooted at v whose bounds hold
+// pt, which is the view mouse events at pt are sent to, or nil if there
+// is none.
+func At(v ui.View, pt image.Point) ui.View {
+	if !pt.In(v.Bounds()) {
+		return nil
+	}
+	for i := 0; i < v.Subviews(); i++ {
+		if at := At(v.Sub(i), pt); at != nil {
+			return at
+		}
+	}
+	return v
+}
+
+type focuser interface {
+	Focused() bool
+}
+
+// Focused returns the view in the tree rooted at v which has the
+// keyboard focus, or nil if there is none.
+func Focused(v ui.View) ui.View {
+	var focused ui.View
+	Walk(v, func(v ui.View) bool {
+		if f, ok := v.(focuser); ok && f.Focused() {
+			focused = v
+			return false
+		}
+		return true
+	})
+	return focused
+}
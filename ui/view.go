@@ -22,26 +22,36 @@ type Receiver interface {
 	Receive(ctl *Controller, event interface{})
 }
 
+// Capturer is implemented by Components which see routed events on
+// their way down to their subviews, before the target receives them.
+type Capturer interface {
+	Capture(ctl *Controller, event interface{})
+}
+
 // Box describes the spatial and hierarchical properties of a View.
 type Box struct {
-	kids   []View
-	bounds image.Rectangle
-	ctl    *Controller
+	parent  *Box
+	kids    []View
+	bounds  image.Rectangle
+	ctl     *Controller
+	focused bool
 }
 
 // setup initializes a View and its Box. Mounts Components.
-func setup(parent *Box, bounds image.Rectangle, view View) {
+func setup(d *dispatcher, parent *Box, bounds image.Rectangle, view View) {
 	box := view.box()
 	if box == nil {
 		panic("ui: box must be non-nil")
 	}
 	*box = Box{
+		parent: parent,
 		bounds: bounds,
 	}
 	if comp, ok := view.(Component); ok {
 		ctl := &Controller{
 			box:  box,
 			comp: comp,
+			d:    d,
 		}
 		box.ctl = ctl
 		comp.Receive(ctl, Mount{})
@@ -74,6 +84,32 @@ func (b *Box) SetBounds(rect image.Rectangle) {
 	b.bounds = rect
 }
 
+// Focused reports whether the Box has the keyboard focus.
+func (b *Box) Focused() bool {
+	return b.focused
+}
+
+// within reports whether b is a or one of its subviews.
+func (b *Box) within(a *Box) bool {
+	for ; b != nil; b = b.parent {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for b and each of its subviews, in tree order.
+func (b *Box) walk(fn func(*Box)) {
+	if b == nil {
+		return
+	}
+	fn(b)
+	for _, k := range b.kids {
+		k.box().walk(fn)
+	}
+}
+
 func (b *Box) hitTest(pt image.Point) *Box {
 	if pt.In(b.bounds) {
 		for _, k := range b.kids {
@@ -104,12 +140,18 @@ func (b *Box) unmount() {
 type Controller struct {
 	box  *Box
 	comp Component
+	d    *dispatcher
+
+	// the phase of the event being received, and whether its
+	// propagation was stopped
+	phase   Phase
+	stopped bool
 }
 
 func (c *Controller) Mount(subviews ...View) {
 	c.box.kids = append(c.box.kids, subviews...)
 	for _, v := range subviews {
-		setup(c.box, c.box.Bounds(), v)
+		setup(c.d, c.box, c.box.Bounds(), v)
 	}
 }
 
@@ -118,6 +160,9 @@ func (c *Controller) Unmount(subviews ...View) {
 	for _, v := range subviews {
 		for i, k := range kids {
 			if k == v {
+				if c.d != nil {
+					c.d.remove(k.box())
+				}
 				k.box().unmount()
 				copy(kids[i:], kids[i+1:])
 				kids = kids[:len(kids)-1]
@@ -125,4 +170,53 @@ func (c *Controller) Unmount(subviews ...View) {
 			}
 		}
 	}
+	c.box.kids = kids
+}
+
+// Phase returns the routing phase of the event being received. For a
+// Component receiving an event it is not the target of, it is
+// BubblePhase.
+func (c *Controller) Phase() Phase {
+	return c.phase
+}
+
+// StopPropagation stops the event being received from going on to any
+// other view, and from causing the default action of Dispatch, such as
+// moving the focus on Tab.
+func (c *Controller) StopPropagation() {
+	c.stopped = true
+}
+
+// Focus gives the keyboard focus to the view, unless the focus is
+// trapped in a subtree the view is not part of.
+func (c *Controller) Focus() {
+	if c.d != nil {
+		c.d.focus(c.box)
+	}
+}
+
+// Blur takes the keyboard focus away from the view, if it has it.
+func (c *Controller) Blur() {
+	if c.d != nil && c.d.keyFocus == c.box {
+		c.d.focus(nil)
+	}
+}
+
+// TrapFocus keeps the keyboard focus within the view and its subviews,
+// as for a modal dialog, until ReleaseFocus is called or the view is
+// unmounted. Focus moves into the view if it is elsewhere. TrapFocus
+// does nothing if the focus is already trapped in a view which this one
+// is not within.
+func (c *Controller) TrapFocus() {
+	if c.d != nil {
+		c.d.trap(c.box)
+	}
+}
+
+// ReleaseFocus undoes TrapFocus, giving the focus back to the view
+// which had it before.
+func (c *Controller) ReleaseFocus() {
+	if c.d != nil {
+		c.d.release(c.box)
+	}
 }
@@ -0,0 +1,91 @@
+package varintrle
+
+import "math"
+
+// Stats summarizes a range of values.
+type Stats struct {
+	Count int
+	Sum   int64
+	Min   int64
+	Max   int64
+	Zeros int
+}
+
+func (s *Stats) add(v int64, n int) {
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count += n
+	s.Sum += v * int64(n)
+	if v == 0 {
+		s.Zeros += n
+	}
+}
+
+// Aggregate summarizes all values of a stream in buf, without decoding
+// it into a slice. Groups of zeros and repeat runs are summarized from
+// their headers alone. Sums that overflow wrap around. Transformed
+// streams are summarized a value at a time, and so return ErrLimit for
+// more values than MaxRepeat allows, as DecodeRun does.
+func Aggregate(buf []byte) (Stats, error) {
+	return AggregateRange(buf, 0, math.MaxInt)
+}
+
+// AggregateRange is like Aggregate, but only summarizes the values at
+// positions from up to to. Groups before from are skipped over by their
+// headers.
+func AggregateRange(buf []byte, from, to int) (Stats, error) {
+	var s Stats
+	p, i, err := parsePreamble(buf)
+	if err == nil {
+		err = p.check(false)
+	}
+	if err == nil && p.version() > MaxVersion {
+		err = ErrVersion
+	}
+	if err != nil {
+		return s, err
+	}
+	// transformed values depend on all the values before them, so only
+	// plain streams can skip or summarize groups whole
+	plain := p.transform() == NoTransform
+	t := transformer{p: p}
+	sc := newScanner(buf[i:], p.version())
+	pos := 0
+	for left := decodeLimit(len(buf)); pos < to; {
+		g, ok := sc.next()
+		if !ok {
+			return s, sc.err
+		}
+		if !plain {
+			if g.n > left {
+				return s, ErrLimit
+			}
+			left -= g.n
+		}
+		start, end := max(from-pos, 0), min(to-pos, g.n)
+		pos += g.n
+		if start >= end {
+			if !plain {
+				for j := 0; j < g.n; j++ {
+					t.undo(unzigzag(g.value(j)))
+				}
+			}
+			continue
+		}
+		if plain && g.uniform() {
+			s.add(unzigzag(g.value(0)), end-start)
+			continue
+		}
+		for j := 0; j < g.n; j++ {
+			v := t.undo(unzigzag(g.value(j)))
+			if j >= start && j < end {
+				s.add(v, 1)
+			}
+		}
+	}
+	return s, nil
+}
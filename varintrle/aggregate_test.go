@@ -0,0 +1,51 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func naiveStats(vals []int64) Stats {
+	var s Stats
+	for _, v := range vals {
+		s.add(v, 1)
+	}
+	return s
+}
+
+func TestAggregate(t *testing.T) {
+	vals := randRepeatSlice(50)
+	for _, opt := range []Options{{}, {Version: 2}, {Version: 3}, {Transform: DeltaOfDelta, Version: 2}} {
+		buf := mustEncode(vals, opt)
+		s, err := Aggregate(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != naiveStats(vals) {
+			t.Fatalf("%+v: expected %+v, got %+v", opt, naiveStats(vals), s)
+		}
+		for k := 0; k < 20; k++ {
+			from := rand.Intn(len(vals))
+			to := from + rand.Intn(len(vals)-from+1)
+			s, err := AggregateRange(buf, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != naiveStats(vals[from:to]) {
+				t.Fatalf("%+v: [%d, %d): expected %+v, got %+v", opt, from, to, naiveStats(vals[from:to]), s)
+			}
+		}
+	}
+
+	// a long repeat run is summarized whole, unless it is transformed
+	run := func(flags uint8) []byte {
+		return binary.AppendUvarint([]byte{preambleHeader, 0, 1<<flagVersionShift | flags, repeatHeader(0)}, 1<<34)
+	}
+	if s, err := Aggregate(run(0)); err != nil || s.Count != 1<<34 {
+		t.Fatalf("expected %d values, got %d: %v", 1<<34, s.Count, err)
+	}
+	if _, err := Aggregate(run(uint8(Delta) << flagTransformShift)); err != ErrLimit {
+		t.Fatalf("expected ErrLimit for a long transformed run, got %v", err)
+	}
+}
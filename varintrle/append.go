@@ -0,0 +1,202 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// payloadMask masks off all but the low n bytes of a value.
+var payloadMask = [9]uint64{
+	0,
+	0xff,
+	0xffff,
+	0xffffff,
+	0xffffffff,
+	0xffffffffff,
+	0xffffffffffff,
+	0xffffffffffffff,
+	0xffffffffffffffff,
+}
+
+// AppendRun appends the encoding of vals to dst and returns the
+// extended buffer. The encoding is the same as WriteRun's. AppendRun
+// does not allocate if dst has enough capacity.
+func AppendRun(dst []byte, vals []int64) []byte {
+	var rw runWriter
+	for _, v := range vals {
+		dst = rw.add(dst, zigzag(v))
+	}
+	return rw.end(dst)
+}
+
+// runWriter groups values as they are appended to a buffer, filling in
+// each group's header once the group is complete.
+type runWriter struct {
+	hdr   int
+	n     int
+	width int
+
+	// if set, the most zeros a group can hold
+	maxZeros int
+}
+
+func (rw *runWriter) add(dst []byte, v uint64) []byte {
+	width := widthOf(v)
+	if rw.full(width) {
+		dst = rw.end(dst)
+		rw.hdr = len(dst)
+		dst = append(dst, 0)
+	}
+	rw.n++
+	rw.width = width
+	return appendPayload(dst, v, width)
+}
+
+// full reports whether a value of the given width needs a new group.
+func (rw *runWriter) full(width int) bool {
+	return rw.n == 0 || width != rw.width || rw.n == 32 || width == 0 && rw.n == rw.maxZeros
+}
+
+// cost returns the number of bytes adding vals would take.
+func (rw runWriter) cost(vals []uint64) int {
+	size := 0
+	for _, v := range vals {
+		width := widthOf(v)
+		if rw.full(width) {
+			size++
+			rw.n = 0
+		}
+		rw.n++
+		rw.width = width
+		if width == 7 {
+			width = 8
+		}
+		size += width
+	}
+	return size
+}
+
+// pending returns the offset in dst of the group still being built.
+func (rw *runWriter) pending(dst []byte) int {
+	if rw.n > 0 {
+		return rw.hdr
+	}
+	return len(dst)
+}
+
+func (rw *runWriter) end(dst []byte) []byte {
+	if rw.n > 0 {
+		dst[rw.hdr] = nbytes(rw.n, rw.width)
+		rw.n = 0
+	}
+	return dst
+}
+
+// appendPayload appends the low width bytes of v to dst, in little
+// endian order. It writes nothing past the bytes it appends, so dst may
+// share its spare capacity with other data.
+func appendPayload(dst []byte, v uint64, width int) []byte {
+	le := binary.LittleEndian
+	switch width {
+	case 0:
+	case 1:
+		dst = append(dst, uint8(v))
+	case 2:
+		dst = le.AppendUint16(dst, uint16(v))
+	case 3:
+		dst = append(le.AppendUint16(dst, uint16(v)), uint8(v>>16))
+	case 4:
+		dst = le.AppendUint32(dst, uint32(v))
+	case 5:
+		dst = append(le.AppendUint32(dst, uint32(v)), uint8(v>>32))
+	case 6:
+		dst = le.AppendUint16(le.AppendUint32(dst, uint32(v)), uint16(v>>32))
+	default:
+		dst = le.AppendUint64(dst, v)
+	}
+	return dst
+}
+
+// DecodeRun decodes all values in src and appends them to dst,
+// returning the extended slice. If src ends in the middle of a group,
+// the values decoded so far are returned with io.ErrUnexpectedEOF.
+// Streams whose repeat runs stand for more values than MaxRepeat allows
+// return ErrLimit, without expanding the run that goes past it.
+// DecodeRun does not allocate if dst has enough capacity.
+func DecodeRun(dst []int64, src []byte) ([]int64, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil && i > 0 {
+		err = p.check(false)
+	}
+	if err != nil {
+		return dst, err
+	}
+	return decodeGroups(dst, src[i:], p, decodeLimit(len(src)))
+}
+
+// decodeGroups decodes the groups in src, which were written as
+// described by p, and appends the values to dst. It returns ErrLimit,
+// without decoding the group, at a group which would take the values
+// decoded past max.
+func decodeGroups(dst []int64, src []byte, p preamble, max int) ([]int64, error) {
+	decode := decodeRun
+	switch p.version() {
+	case 1:
+	case 2:
+		decode = decodeRun2
+	case 3:
+		decode = decodeRun3
+	default:
+		return dst, ErrVersion
+	}
+	if p.transform() == NoTransform {
+		return decode(dst, src, max)
+	}
+	start := len(dst)
+	dst, err := decode(dst, src, max)
+	t := transformer{p: p}
+	for j, r := range dst[start:] {
+		dst[start+j] = t.undo(r)
+	}
+	return dst, err
+}
+
+func decodeRun(dst []int64, src []byte, max int) ([]int64, error) {
+	i := 0
+	for left := max; i < len(src); {
+		n, bytes := getnbytes(src[i])
+		if n > left {
+			return dst, ErrLimit
+		}
+		left -= n
+		i++
+		if bytes == 0 {
+			for ; n > 0; n-- {
+				dst = append(dst, 0)
+			}
+			continue
+		}
+		if len(src)-i < n*bytes {
+			return dst, io.ErrUnexpectedEOF
+		}
+		mask := payloadMask[bytes]
+		for ; n > 0; n-- {
+			dst = append(dst, unzigzag(readPayload(src, i, bytes, mask)))
+			i += bytes
+		}
+	}
+	return dst, nil
+}
+
+// readPayload reads a value of the given size in bytes from src at i.
+// mask must be payloadMask[bytes].
+func readPayload(src []byte, i, bytes int, mask uint64) uint64 {
+	if len(src)-i >= 8 {
+		return binary.LittleEndian.Uint64(src[i:]) & mask
+	}
+	var val uint64
+	for j := 0; j < bytes; j++ {
+		val |= uint64(src[i+j]) << (uint64(j) * 8)
+	}
+	return val
+}
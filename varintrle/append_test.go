@@ -0,0 +1,83 @@
+package varintrle
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestAppendRun(t *testing.T) {
+	vals := randIntSlice(20)
+	expected := &bytes.Buffer{}
+	err := WriteRun(expected, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := []byte("prefix")
+	buf := AppendRun(prefix, vals)
+	if !bytes.Equal(buf[:len(prefix)], prefix) {
+		t.Fatalf("did not preserve dst")
+	}
+	if !bytes.Equal(buf[len(prefix):], expected.Bytes()) {
+		t.Fatalf("did not match WriteRun output")
+	}
+	actual, err := DecodeRun([]int64{42}, buf[len(prefix):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual[0] != 42 || !reflect.DeepEqual(vals, actual[1:]) {
+		t.Fatalf("did not match expected output")
+	}
+}
+
+func TestAppendRunSpare(t *testing.T) {
+	// bytes past the end of dst belong to the caller
+	buf := bytes.Repeat([]byte{0xaa}, 1000)
+	out := AppendRun(buf[:0], []int64{1, 300, 1 << 20, 1 << 40, -5})
+	for i, b := range buf[len(out):] {
+		if b != 0xaa {
+			t.Fatalf("wrote to byte %d past the end of the output", i)
+		}
+	}
+}
+
+func TestAppendRunAllocs(t *testing.T) {
+	vals := randIntSlice(20)
+	buf := make([]byte, 0, len(vals)*9)
+	dst := make([]int64, 0, len(vals))
+	allocs := testing.AllocsPerRun(10, func() {
+		buf = AppendRun(buf[:0], vals)
+		dst, _ = DecodeRun(dst[:0], buf)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkAppendRunRandom(b *testing.B) {
+	rand.Seed(1)
+	vals := randIntSlice(100)
+	b.SetBytes(100 * 8)
+	buf := make([]byte, 0, len(vals)*9)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = AppendRun(buf[:0], vals)
+	}
+}
+
+func BenchmarkDecodeRunRandom(b *testing.B) {
+	rand.Seed(1)
+	vals := randIntSlice(100)
+	b.SetBytes(100 * 8)
+	buf := AppendRun(nil, vals)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := DecodeRun(vals[:0], buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package varintrle
+
+// A Strategy is a way of encoding a column of values, as chosen by
+// EncodeAuto.
+type Strategy uint8
+
+const (
+	// RawStrategy zigzag encodes values as they are, as AppendRun does.
+	RawStrategy Strategy = iota
+	// UnsignedStrategy stores values without zigzag encoding, as
+	// AppendRunUint64 does, which only works if none are negative.
+	UnsignedStrategy
+	// DeltaStrategy uses the Delta transform.
+	DeltaStrategy
+	// DeltaOfDeltaStrategy uses the DeltaOfDelta transform.
+	DeltaOfDeltaStrategy
+
+	numStrategies
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case RawStrategy:
+		return "raw"
+	case UnsignedStrategy:
+		return "unsigned"
+	case DeltaStrategy:
+		return "delta"
+	case DeltaOfDeltaStrategy:
+		return "delta-of-delta"
+	}
+	return "unknown"
+}
+
+// Inputs longer than maxSample values are analyzed from sampleBlocks
+// runs of consecutive values spread evenly through them, so that deltas
+// are still taken between neighbours.
+const (
+	maxSample    = 1 << 16
+	sampleBlocks = 16
+)
+
+// A Report is the result of Analyze.
+type Report struct {
+	// Size holds the estimated encoded size in bytes of the values
+	// under each strategy, or -1 where a strategy cannot encode them.
+	Size [numStrategies]int
+
+	// Sampled is set if the sizes were estimated from a sample of the
+	// values rather than all of them.
+	Sampled bool
+}
+
+// Best returns the strategy giving the smallest encoding.
+func (r Report) Best() Strategy {
+	best := RawStrategy
+	for s := range numStrategies {
+		if r.Size[s] >= 0 && r.Size[s] < r.Size[best] {
+			best = s
+		}
+	}
+	return best
+}
+
+// Analyze estimates the encoded size of vals under each strategy.
+// Large inputs are estimated from a sample of their values.
+func Analyze(vals []int64) Report {
+	var r Report
+	sample := [][]int64{vals}
+	if len(vals) > maxSample {
+		r.Sampled = true
+		sample = sample[:0]
+		k := maxSample / sampleBlocks
+		step := (len(vals) - k) / (sampleBlocks - 1)
+		for i := 0; i < sampleBlocks; i++ {
+			sample = append(sample, vals[i*step:i*step+k])
+		}
+	}
+	var (
+		buf []byte
+		n   int
+	)
+	for _, vals := range sample {
+		n += len(vals)
+		for s := range numStrategies {
+			if r.Size[s] < 0 {
+				continue
+			}
+			if s == UnsignedStrategy && hasNegative(vals) {
+				r.Size[s] = -1
+				continue
+			}
+			buf = appendStrategy(buf[:0], vals, s)
+			r.Size[s] += len(buf)
+		}
+	}
+	if r.Sampled {
+		for s := range r.Size {
+			if r.Size[s] > 0 {
+				r.Size[s] = int(int64(r.Size[s]) * int64(len(vals)) / int64(n))
+			}
+		}
+	}
+	// a sample may miss the negative values
+	if r.Size[UnsignedStrategy] >= 0 && hasNegative(vals) {
+		r.Size[UnsignedStrategy] = -1
+	}
+	return r
+}
+
+func hasNegative(vals []int64) bool {
+	for _, v := range vals {
+		if v < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func appendStrategy(dst []byte, vals []int64, s Strategy) []byte {
+	switch s {
+	case UnsignedStrategy:
+		dst = appendPreamble(dst, preamble{flags: flagUnsigned})
+		var rw runWriter
+		for _, v := range vals {
+			dst = rw.add(dst, uint64(v))
+		}
+		return rw.end(dst)
+	case DeltaStrategy:
+		return appendRunOptions(dst, vals, Options{Transform: Delta})
+	case DeltaOfDeltaStrategy:
+		return appendRunOptions(dst, vals, Options{Transform: DeltaOfDelta})
+	}
+	return AppendRun(dst, vals)
+}
+
+// EncodeAuto appends vals to dst, encoded with the strategy Analyze
+// finds best for them, and returns the extended buffer. The strategy is
+// recorded in the preamble of the stream, which can be read back with
+// DecodeAuto.
+func EncodeAuto(dst []byte, vals []int64) []byte {
+	return appendStrategy(dst, vals, Analyze(vals).Best())
+}
+
+// DecodeAuto is like DecodeRun, but also decodes streams of unsigned
+// values, such as those written by EncodeAuto. Unsigned values too
+// large for an int64 wrap around. Like DecodeRun, it returns ErrLimit
+// for streams of more values than MaxRepeat allows.
+func DecodeAuto(dst []int64, src []byte) ([]int64, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil && i > 0 {
+		err = p.check(p.flags&flagUnsigned != 0)
+	}
+	if err != nil {
+		return dst, err
+	}
+	start := len(dst)
+	dst, err = decodeGroups(dst, src[i:], p, decodeLimit(len(src)))
+	if p.flags&flagUnsigned != 0 {
+		for j, v := range dst[start:] {
+			dst[start+j] = int64(zigzag(v))
+		}
+	}
+	return dst, err
+}
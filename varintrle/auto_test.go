@@ -0,0 +1,70 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeAuto(t *testing.T) {
+	counter := make([]int64, 1000)
+	for i := range counter {
+		counter[i] = 1 << 40
+		if i > 0 {
+			counter[i] = counter[i-1]
+		}
+		if i%10 == 0 {
+			counter[i] += int64(i % 7)
+		}
+	}
+	unsigned := make([]int64, 1000)
+	for i := range unsigned {
+		if i%2 == 1 {
+			unsigned[i] = int64(200 + i%50)
+		}
+	}
+	table := []struct {
+		vals []int64
+		best Strategy
+	}{
+		{randIntSlice(50), RawStrategy},
+		{unsigned, UnsignedStrategy},
+		{counter, DeltaStrategy},
+		{timestamps(1000), DeltaOfDeltaStrategy},
+		{timestamps(maxSample * 3), DeltaOfDeltaStrategy},
+	}
+	for i, tt := range table {
+		r := Analyze(tt.vals)
+		if r.Best() != tt.best {
+			t.Fatalf("%d: expected %v, got %v (%v)", i, tt.best, r.Best(), r.Size)
+		}
+		if r.Sampled != (len(tt.vals) > maxSample) {
+			t.Fatalf("%d: unexpected Sampled %v", i, r.Sampled)
+		}
+		buf := EncodeAuto(nil, tt.vals)
+		if !r.Sampled && len(buf) != r.Size[tt.best] {
+			t.Fatalf("%d: estimated %d bytes, encoded %d", i, r.Size[tt.best], len(buf))
+		}
+		actual, err := DecodeAuto(nil, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.vals, actual) {
+			t.Fatalf("%d: did not match expected output", i)
+		}
+	}
+
+	hostile := binary.AppendUvarint([]byte{preambleHeader, 0, 1<<flagVersionShift | flagUnsigned, repeatHeader(0)}, 1<<34)
+	if actual, err := DecodeAuto(nil, hostile); err != ErrLimit || cap(actual) != 0 {
+		t.Fatalf("expected ErrLimit for a long repeat run, got %v", err)
+	}
+}
+
+func TestAnalyzeNegativeOutsideSample(t *testing.T) {
+	vals := make([]int64, maxSample*2)
+	vals[maxSample/sampleBlocks+1] = -1
+	r := Analyze(vals)
+	if r.Size[UnsignedStrategy] != -1 {
+		t.Fatalf("expected the unsigned strategy to be ruled out, got %v", r.Size)
+	}
+}
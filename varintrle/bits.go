@@ -0,0 +1,73 @@
+package varintrle
+
+// Version 2 streams may also hold groups of up to 32 values packed to a
+// width in bits rather than bytes, so that small values take only the
+// bits they need. The header of such a group is followed by a byte
+// giving its length and the rest of its width:
+//
+//	1eeee000  e >= 8:   a bit-packed group, followed by
+//	bbbnnnnn            n+1 values of (e-8)<<3|b + 1 bits each
+//
+// and then the values, packed least significant bit first. These
+// headers were free in version 2 before it was released, and are part
+// of it from the start; see the package doc on compatibility.
+
+// isBitGroup reports whether the extended header b starts a bit-packed
+// group rather than a repeat run.
+func isBitGroup(b uint8) bool {
+	return b&0x40 != 0
+}
+
+func bitsHeader(n, nbits int) (uint8, uint8) {
+	hi := (nbits - 1) >> 3
+	lo := (nbits - 1) & 7
+	return headerRepeat | uint8(8+hi)<<3, uint8(lo)<<5 | uint8(n-1)
+}
+
+func getbits(b, c uint8) (n, nbits int) {
+	return int(c&31) + 1, int((b>>3)&7)<<3 | int(c>>5) + 1
+}
+
+// bitsSize returns the size in bytes of n values of nbits bits.
+func bitsSize(n, nbits int) int {
+	return (n*nbits + 7) / 8
+}
+
+func appendBitGroup(dst []byte, vals []uint64, nbits int) []byte {
+	b, c := bitsHeader(len(vals), nbits)
+	dst = append(dst, b, c)
+	return appendBits(dst, vals, nbits)
+}
+
+// appendBits appends vals packed to nbits bits each.
+func appendBits(dst []byte, vals []uint64, nbits int) []byte {
+	start := len(dst)
+	for k := bitsSize(len(vals), nbits); k > 0; k-- {
+		dst = append(dst, 0)
+	}
+	out := dst[start:]
+	for i, v := range vals {
+		off := i * nbits
+		for k := 0; k < nbits; {
+			s := (off + k) & 7
+			out[(off+k)>>3] |= uint8(v >> uint(k) << uint(s))
+			k += 8 - s
+		}
+	}
+	return dst
+}
+
+// readBits reads the i'th value of nbits bits from data.
+func readBits(data []byte, i, nbits int) uint64 {
+	off := i * nbits
+	var v uint64
+	for k := 0; k < nbits; {
+		s := (off + k) & 7
+		v |= uint64(data[(off+k)>>3]>>uint(s)) << uint(k)
+		k += 8 - s
+	}
+	if nbits < 64 {
+		v &= 1<<uint(nbits) - 1
+	}
+	return v
+}
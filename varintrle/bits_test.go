@@ -0,0 +1,60 @@
+package varintrle
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBitPacking(t *testing.T) {
+	opt := Options{Version: 2}
+	small := make([]int64, 1000)
+	for i := range small {
+		small[i] = int64(rand.Intn(8)) - 4
+	}
+	buf := mustEncode(small, opt)
+	// 3 bits a value, less the preamble and headers
+	if len(buf) > len(small)*3/8+len(small)/32*2+8 {
+		t.Fatalf("expected small values to be bit-packed, got %d bytes", len(buf))
+	}
+
+	var vals []int64
+	for nbits := 1; nbits <= 64; nbits++ {
+		for i := 0; i < 40; i++ {
+			vals = append(vals, unzigzag(rand.Uint64()>>uint(64-nbits)))
+		}
+	}
+	for _, vals := range [][]int64{small, vals} {
+		buf := mustEncode(vals, opt)
+		actual, err := DecodeRun(nil, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("DecodeRun did not match expected output")
+		}
+		actual = actual[:0]
+		dec := NewDecoder(bytes.NewReader(buf))
+		for v := range dec.All() {
+			actual = append(actual, v)
+		}
+		if dec.Err() != nil {
+			t.Fatal(dec.Err())
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("Decoder did not match expected output")
+		}
+		sliced, err := Slice(nil, buf, 5, len(vals)-7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err = DecodeRun(actual[:0], sliced)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals[5:len(vals)-7], actual) {
+			t.Fatalf("Slice did not match expected output")
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Command varintrle encodes, decodes and inspects varintrle streams and
+// containers.
+//
+// Usage:
+//
+//	varintrle encode [flags] < values > file
+//	varintrle decode < file
+//	varintrle inspect < file
+//	varintrle verify [-n count] < file
+//
+// encode reads decimal integers separated by white space, or a column
+// of CSV with -csv, and writes them out as a stream, or as a container
+// with -container. decode prints the values of a stream or container,
+// one per line. inspect prints each group of a stream along with its
+// offset, count, width and the values as stored, and for a container,
+// the offset and count of each block before its groups. Widths are in
+// bytes, marked r for repeat runs, or in bits, marked b. verify decodes a
+// stream or container, checking its checksums and value counts.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"j4k.co/exp/varintrle"
+)
+
+var commands = map[string]func(args []string, in io.Reader, out io.Writer) error{
+	"encode":  encode,
+	"decode":  decode,
+	"inspect": inspect,
+	"verify":  verify,
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: varintrle encode|decode|inspect|verify [flags] < input")
+		os.Exit(2)
+	}
+	err := commands[os.Args[1]](os.Args[2:], os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+var transforms = map[string]varintrle.Transform{
+	"none":           varintrle.NoTransform,
+	"delta":          varintrle.Delta,
+	"delta-of-delta": varintrle.DeltaOfDelta,
+	"for":            varintrle.FrameOfReference,
+}
+
+func encode(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	var (
+		useCSV    = fs.Bool("csv", false, "read values from a column of CSV")
+		column    = fs.Int("column", 0, "CSV column to read, counting from 0")
+		header    = fs.Bool("header", false, "skip the first CSV record")
+		transform = fs.String("transform", "none", "transform: none, delta, delta-of-delta, for or auto")
+		version   = fs.Int("version", 1, "format version")
+		container = fs.Bool("container", false, "write a checksummed container rather than a stream")
+		blockSize = fs.Int("blocksize", varintrle.DefaultBlockSize, "values per container block")
+		index     = fs.Bool("index", false, "add a block index to the container")
+	)
+	fs.Parse(args)
+
+	var (
+		vals []int64
+		err  error
+	)
+	if *useCSV {
+		vals, err = readCSV(in, *column, *header)
+	} else {
+		vals, err = readValues(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *transform == "auto" {
+		if *container || *version != 1 {
+			return errors.New("-transform auto writes version 1 streams only")
+		}
+		_, err = out.Write(varintrle.EncodeAuto(nil, vals))
+		return err
+	}
+	t, ok := transforms[*transform]
+	if !ok {
+		return fmt.Errorf("unknown transform %q", *transform)
+	}
+	if *version < 1 || *version > varintrle.MaxVersion {
+		return fmt.Errorf("version must be from 1 to %d", varintrle.MaxVersion)
+	}
+	opt := varintrle.Options{
+		Transform: t,
+		Version:   *version,
+		BlockSize: *blockSize,
+		Index:     *index,
+	}
+	var buf []byte
+	if *container {
+		buf, err = varintrle.Marshal(vals, opt)
+	} else {
+		buf, err = varintrle.AppendRunOptions(nil, vals, opt)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(buf)
+	return err
+}
+
+func readValues(r io.Reader) ([]int64, error) {
+	var vals []int64
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	for s.Scan() {
+		v, err := strconv.ParseInt(s.Text(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	return vals, s.Err()
+}
+
+func readCSV(r io.Reader, column int, header bool) ([]int64, error) {
+	var vals []int64
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return vals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header && line == 1 {
+			continue
+		}
+		if column >= len(rec) {
+			return nil, fmt.Errorf("line %d: no column %d", line, column)
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(rec[column]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		vals = append(vals, v)
+	}
+}
+
+// readAll decodes the values of the container or stream in data, and
+// reports whether it was a container. A stream can start with the same
+// bytes as a container, so data is only taken to be one if it holds a
+// whole valid container, or if it is not a valid stream either, in
+// which case the error is the container's.
+func readAll(data []byte) (vals []int64, container bool, err error) {
+	if !bytes.HasPrefix(data, []byte("vrle")) {
+		vals, err = varintrle.DecodeAuto(nil, data)
+		return vals, false, err
+	}
+	cvals, cerr := varintrle.Unmarshal(data)
+	if cerr == nil {
+		return cvals, true, nil
+	}
+	if vals, err = varintrle.DecodeAuto(nil, data); err == nil {
+		return vals, false, nil
+	}
+	return cvals, true, cerr
+}
+
+func decode(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Parse(args)
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	vals, _, err := readAll(data)
+	w := bufio.NewWriter(out)
+	for _, v := range vals {
+		fmt.Fprintln(w, v)
+	}
+	if werr := w.Flush(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+func inspect(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Parse(args)
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	if _, container, _ := readAll(data); container {
+		return inspectContainer(w, data)
+	}
+	info, err := varintrle.Inspect(data, func(varintrle.GroupInfo) bool { return false })
+	if err != nil {
+		return err
+	}
+	if info.Size > 0 {
+		fmt.Fprintf(w, "preamble: %d bytes, version %d, transform %s, unsigned %v, ref %d\n",
+			info.Size, info.Version, transformName(info.Transform), info.Unsigned, info.Ref)
+	}
+	printGroupHeader(w)
+	_, err = varintrle.Inspect(data, func(g varintrle.GroupInfo) bool {
+		printGroup(w, g)
+		return true
+	})
+	return err
+}
+
+// inspectContainer prints each block of the container in data, and
+// then its groups.
+func inspectContainer(w io.Writer, data []byte) error {
+	info, err := varintrle.InspectContainer(data, func(varintrle.BlockInfo) bool { return false })
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "container: %d bytes of header, version %d, transform %s\n",
+		info.Size, info.Version, transformName(info.Transform))
+	_, err = varintrle.InspectContainer(data, func(b varintrle.BlockInfo) bool {
+		fmt.Fprintf(w, "block at %d: %d values", b.Offset, b.Count)
+		if info.Transform == varintrle.FrameOfReference {
+			fmt.Fprintf(w, ", ref %d", b.Ref)
+		}
+		fmt.Fprintln(w)
+		printGroupHeader(w)
+		for _, g := range b.Groups {
+			printGroup(w, g)
+		}
+		return true
+	})
+	return err
+}
+
+func printGroupHeader(w io.Writer) {
+	fmt.Fprintf(w, "%-8s %5s %5s  %s\n", "offset", "count", "width", "values")
+}
+
+func printGroup(w io.Writer, g varintrle.GroupInfo) {
+	width := strconv.Itoa(g.Width)
+	switch {
+	case g.Bits > 0:
+		width = fmt.Sprintf("%db", g.Bits)
+	case g.Repeat:
+		width += "r"
+	}
+	fmt.Fprintf(w, "%-8d %5d %5s  %v\n", g.Offset, g.Count, width, g.Values)
+}
+
+func transformName(t varintrle.Transform) string {
+	for name, tt := range transforms {
+		if tt == t {
+			return name
+		}
+	}
+	return strconv.Itoa(int(t))
+}
+
+func verify(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	count := fs.Int("n", -1, "expected number of values")
+	fs.Parse(args)
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	vals, container, err := readAll(data)
+	if err != nil {
+		return fmt.Errorf("after %d values: %v", len(vals), err)
+	}
+	if *count >= 0 && len(vals) != *count {
+		return fmt.Errorf("expected %d values, found %d", *count, len(vals))
+	}
+	kind := "stream"
+	if container {
+		kind = "container"
+	}
+	_, err = fmt.Fprintf(out, "ok: %s of %d values in %d bytes\n", kind, len(vals), len(data))
+	return err
+}
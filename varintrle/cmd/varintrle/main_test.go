@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"j4k.co/exp/varintrle"
+)
+
+func run(t *testing.T, cmd string, in []byte, args ...string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	if err := commands[cmd](args, bytes.NewReader(in), &out); err != nil {
+		t.Fatalf("%s %v: %v", cmd, args, err)
+	}
+	return out.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	const input = "1\n2\n3\n3\n3\n-7\n1000000\n"
+	for _, args := range [][]string{
+		nil,
+		{"-version", "2", "-transform", "delta"},
+		{"-version", "3"},
+		{"-transform", "auto"},
+		{"-container", "-blocksize", "2"},
+		{"-container", "-index", "-version", "2", "-transform", "for"},
+	} {
+		data := run(t, "encode", []byte(input), args...)
+		if out := run(t, "decode", data); string(out) != input {
+			t.Fatalf("%v: decoded %q", args, out)
+		}
+		kind := "stream"
+		if len(args) > 0 && args[0] == "-container" {
+			kind = "container"
+		}
+		if out := run(t, "inspect", data); !strings.Contains(string(out), "offset") {
+			t.Fatalf("%v: unexpected inspect output %q", args, out)
+		} else if kind == "container" && !strings.Contains(string(out), "block at ") {
+			t.Fatalf("%v: expected blocks in inspect output %q", args, out)
+		}
+		expected := "ok: " + kind + " of 7 values"
+		if out := run(t, "verify", data, "-n", "7"); !strings.HasPrefix(string(out), expected) {
+			t.Fatalf("%v: expected %q, got %q", args, expected, out)
+		}
+	}
+}
+
+func TestStreamLikeContainer(t *testing.T) {
+	// a version 1 stream which starts with the magic of a container
+	data := []byte("vrle")
+	for len(data) < 1024 {
+		if _, err := varintrle.DecodeRun(nil, data); err == nil {
+			break
+		}
+		data = append(data, 0)
+	}
+	vals, err := varintrle.DecodeRun(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := run(t, "verify", data); !strings.HasPrefix(string(out), "ok: stream") {
+		t.Fatalf("expected a stream, got %q", out)
+	}
+	if out := run(t, "decode", data); strings.Count(string(out), "\n") != len(vals) {
+		t.Fatalf("expected %d values, got %q", len(vals), out)
+	}
+	run(t, "inspect", data)
+
+	// while a damaged container is still reported as one
+	data = run(t, "encode", []byte("1 2 3"), "-container")
+	data[len(data)-1] ^= 1
+	var out bytes.Buffer
+	if err := verify(nil, bytes.NewReader(data), &out); err == nil || !strings.Contains(err.Error(), varintrle.ErrChecksum.Error()) {
+		t.Fatalf("expected a checksum error, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrMismatch is returned by Concat for streams written with
+	// different options, whose groups cannot be put in one stream.
+	ErrMismatch = errors.New("varintrle: streams were written with different options")
+	// ErrTransformed is returned by Concat and Slice for streams
+	// written with a transform, whose values depend on the values
+	// before them, and so cannot be moved without decoding them.
+	ErrTransformed = errors.New("varintrle: transformed streams cannot be joined or cut without decoding")
+)
+
+// groupWriter writes out whole groups, copying their payloads as is.
+// The last group written is kept open, so that values can be added to
+// it.
+type groupWriter struct {
+	version int
+	split   splitWriter
+
+	// the open group, if n > 0, and the offset of its header
+	hdr   int
+	n     int
+	bytes int
+}
+
+func (w *groupWriter) maxN(bytes int) int {
+	if bytes == 0 && w.version == 2 {
+		return maxZeros2
+	}
+	return 32
+}
+
+// write writes out g, merging as many of its values into the open group
+// as it can if merge is set.
+func (w *groupWriter) write(dst []byte, g group, merge bool) []byte {
+	if merge && !g.repeat && g.bits == 0 && w.n > 0 && g.bytes == w.bytes {
+		k := min(g.n, w.maxN(g.bytes)-w.n)
+		dst = w.extend(dst, g.data[:k*g.bytes], k)
+		g.n -= k
+		g.data = g.data[k*g.bytes:]
+		if g.n == 0 {
+			return dst
+		}
+	}
+	if g.repeat {
+		w.n = 0
+		dst = append(dst, repeatHeader(g.bytes))
+		dst = binary.AppendUvarint(dst, uint64(g.n))
+		return append(dst, g.data...)
+	}
+	if g.bits > 0 {
+		w.n = 0
+		b, c := bitsHeader(g.n, g.bits)
+		dst = append(dst, b, c)
+		return append(dst, g.data...)
+	}
+	if w.version == 3 {
+		if len(w.split.ctrl) == maxChunkGroups {
+			dst = w.split.flush(dst)
+		}
+		w.hdr = len(w.split.ctrl)
+		w.split.ctrl = append(w.split.ctrl, nbytes(g.n, g.bytes))
+		w.split.data = append(w.split.data, g.data...)
+	} else {
+		w.hdr = len(dst)
+		dst = append(dst, nbytes(g.n, g.bytes))
+		dst = append(dst, g.data...)
+	}
+	w.n = g.n
+	w.bytes = g.bytes
+	return dst
+}
+
+// extend adds k values with the given payloads to the open group.
+func (w *groupWriter) extend(dst []byte, data []byte, k int) []byte {
+	w.n += k
+	if w.version == 3 {
+		w.split.ctrl[w.hdr] = nbytes(w.n, w.bytes)
+		w.split.data = append(w.split.data, data...)
+		return dst
+	}
+	dst[w.hdr] = nbytes(w.n, w.bytes)
+	return append(dst, data...)
+}
+
+func (w *groupWriter) end(dst []byte) []byte {
+	w.n = 0
+	if w.version == 3 {
+		return w.split.flush(dst)
+	}
+	return dst
+}
+
+// slice returns the values of g from i up to j.
+func (g group) slice(i, j int) group {
+	if g.bits > 0 && j-i < g.n {
+		vals := make([]uint64, 0, j-i)
+		for k := i; k < j; k++ {
+			vals = append(vals, g.value(k))
+		}
+		g.data = appendBits(nil, vals, g.bits)
+	} else if g.bits == 0 && !g.repeat {
+		g.data = g.data[i*g.bytes : j*g.bytes]
+	}
+	g.n = j - i
+	return g
+}
+
+// Concat appends to dst a stream holding the values of all of streams,
+// one after another, and returns the extended buffer. The streams must
+// all be written with the same options, and without a transform. The
+// groups of each stream are copied as they are, except where the end of
+// one stream can take values from the start of the next.
+//
+// Concat reads the headers of every group it copies, so it returns an
+// error, along with what it appended so far, for streams it cannot
+// join: ErrMismatch for streams with different options,
+// ErrTransformed, or the error DecodeRun would return for a damaged
+// stream.
+func Concat(dst []byte, streams ...[]byte) ([]byte, error) {
+	var (
+		w       groupWriter
+		first   []byte
+		started bool
+	)
+	for _, src := range streams {
+		if len(src) == 0 {
+			continue
+		}
+		p, i, err := parsePreamble(src)
+		if err == nil {
+			err = checkCopyable(p)
+		}
+		if err != nil {
+			return dst, err
+		}
+		if !started {
+			started = true
+			first = src[:i]
+			dst = append(dst, first...)
+			w.version = p.version()
+		} else if string(src[:i]) != string(first) {
+			return dst, ErrMismatch
+		}
+		s := newScanner(src[i:], p.version())
+		merge := true
+		for {
+			g, ok := s.next()
+			if !ok {
+				if s.err != nil {
+					return dst, s.err
+				}
+				break
+			}
+			dst = w.write(dst, g, merge)
+			merge = false
+		}
+	}
+	return w.end(dst), nil
+}
+
+// Slice appends to dst a stream holding the values of src at positions
+// from up to to, and returns the extended buffer. src must be written
+// without a transform. Only the groups cut by from and to are
+// rewritten, and the rest are copied as they are.
+//
+// Like Concat, and AppendRun, Slice appends to dst, so that cutting
+// many windows out of a stream can reuse one buffer. It returns
+// ErrTransformed for transformed streams, an error for a range past
+// the end of src, and the error DecodeRun would return for a damaged
+// stream.
+func Slice(dst []byte, src []byte, from, to int) ([]byte, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil {
+		err = checkCopyable(p)
+	}
+	if err != nil {
+		return dst, err
+	}
+	if from < 0 || to < from {
+		return dst, errRange
+	}
+	dst = append(dst, src[:i]...)
+	w := groupWriter{version: p.version()}
+	s := newScanner(src[i:], p.version())
+	pos := 0
+	for pos < to {
+		g, ok := s.next()
+		if !ok {
+			if s.err != nil {
+				return dst, s.err
+			}
+			return dst, errRange
+		}
+		start, end := max(from-pos, 0), min(to-pos, g.n)
+		pos += g.n
+		if start < end {
+			dst = w.write(dst, g.slice(start, end), false)
+		}
+	}
+	return w.end(dst), nil
+}
+
+func checkCopyable(p preamble) error {
+	if p.flags&flagFloat != 0 {
+		return ErrFloat
+	}
+	if p.version() > MaxVersion {
+		return ErrVersion
+	}
+	if p.transform() != NoTransform {
+		return ErrTransformed
+	}
+	return nil
+}
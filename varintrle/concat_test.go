@@ -0,0 +1,77 @@
+package varintrle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConcatSlice(t *testing.T) {
+	for version := 1; version <= MaxVersion; version++ {
+		opt := Options{Version: version}
+		var (
+			streams [][]byte
+			all     []int64
+		)
+		for i := 0; i < 4; i++ {
+			vals := randRepeatSlice(10)
+			streams = append(streams, mustEncode(vals, opt))
+			all = append(all, vals...)
+		}
+		buf, err := Concat(nil, streams...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := DecodeRun(nil, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(all, actual) {
+			t.Fatalf("version %d: concat did not match expected output", version)
+		}
+
+		for _, r := range [][2]int{{0, 0}, {0, len(all)}, {3, 5}, {7, len(all) - 9}, {len(all) / 2, len(all)}} {
+			sliced, err := Slice(nil, buf, r[0], r[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := DecodeRun(nil, sliced)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actual) != r[1]-r[0] || len(actual) > 0 && !reflect.DeepEqual(all[r[0]:r[1]], actual) {
+				t.Fatalf("version %d: slice [%d:%d] did not match expected output", version, r[0], r[1])
+			}
+		}
+		if _, err := Slice(nil, buf, 0, len(all)+1); err != errRange {
+			t.Fatalf("expected errRange, got %v", err)
+		}
+	}
+}
+
+func TestConcatSeam(t *testing.T) {
+	a := AppendRun(nil, []int64{1, 2, 3})
+	b := AppendRun(nil, []int64{4, 5})
+	buf, err := Concat(nil, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AppendRun(nil, []int64{1, 2, 3, 4, 5})
+	if !reflect.DeepEqual(buf, expected) {
+		t.Fatalf("expected seam groups to be merged, got %x", buf)
+	}
+}
+
+func TestConcatErrors(t *testing.T) {
+	a := mustEncode([]int64{1, 2, 3}, Options{Version: 2})
+	b := AppendRun(nil, []int64{4, 5})
+	if _, err := Concat(nil, a, b); err != ErrMismatch {
+		t.Fatalf("expected ErrMismatch, got %v", err)
+	}
+	c := mustEncode([]int64{1, 2, 3}, Options{Transform: Delta})
+	if _, err := Concat(nil, c, c); err != ErrTransformed {
+		t.Fatalf("expected ErrTransformed, got %v", err)
+	}
+	if _, err := Slice(nil, c, 0, 1); err != ErrTransformed {
+		t.Fatalf("expected ErrTransformed, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// Marshal and Unmarshal use a container format, meant for storage, that
+// wraps values in blocks which can each be checked for damage:
+//
+//	magic    "vrle"
+//	version  format version of the blocks
+//	flags    transform, as in a stream preamble, and whether the
+//	         blocks are followed by an index
+//	count    uvarint number of values
+//	blocks   until count values are read
+//	index    optional, see Reader
+//
+// with each block holding
+//
+//	n        uvarint number of values
+//	size     uvarint size of data
+//	data     varint reference if FrameOfReference, then the groups
+//	crc      CRC-32C of data, little endian
+//
+// Blocks are encoded independently of each other, so that transforms
+// start over in each block.
+const (
+	containerMagic   = "vrle"
+	containerVersion = 4
+	containerFlags   = 5
+	containerHeader  = 6
+
+	// DefaultBlockSize is the number of values in each block of a
+	// container, if not set in Options.
+	DefaultBlockSize = 4096
+)
+
+var (
+	// ErrCorrupt is returned when data is not in the expected format.
+	ErrCorrupt = errors.New("varintrle: corrupt data")
+	// ErrTruncated is returned when data ends before all of its values
+	// have been read.
+	ErrTruncated = errors.New("varintrle: truncated data")
+	// ErrChecksum is returned when a block's data does not match its
+	// checksum.
+	ErrChecksum = errors.New("varintrle: checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func (opt Options) blockSize() int {
+	if opt.BlockSize <= 0 {
+		return DefaultBlockSize
+	}
+	return opt.BlockSize
+}
+
+// Marshal encodes vals as described by opt in the container format. It
+// returns ErrVersion if opt.Version is not supported, and ErrTransform
+// if opt.Transform is unknown.
+func Marshal(vals []int64, opt Options) ([]byte, error) {
+	if err := opt.check(); err != nil {
+		return nil, err
+	}
+	p := makePreamble(opt)
+	dst := make([]byte, 0, containerHeader+binary.MaxVarintLen64+len(vals)*2)
+	dst = append(dst, containerMagic...)
+	dst = append(dst, byte(p.version()), p.flags&flagTransformMask)
+	dst = binary.AppendUvarint(dst, uint64(len(vals)))
+	if opt.Index {
+		dst[containerFlags] |= containerIndexed
+	}
+	size := opt.blockSize()
+	var index []blockInfo
+	for pos := 0; pos < len(vals); pos += size {
+		n := min(size, len(vals)-pos)
+		index = append(index, blockInfo{
+			offset: int64(len(dst)),
+			first:  int64(pos),
+		})
+		dst = appendBlock(dst, vals[pos:pos+n], p)
+	}
+	if opt.Index {
+		dst = appendIndex(dst, index)
+	}
+	return dst, nil
+}
+
+func appendBlock(dst []byte, vals []int64, p preamble) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(vals)))
+	// the size is not known until the body is written, so write the
+	// body after room for the largest size, and move it into place
+	hdr := len(dst)
+	dst = append(dst, make([]byte, binary.MaxVarintLen64)...)
+	start := len(dst)
+	if p.transform() == FrameOfReference {
+		p.ref = midpoint(vals)
+		dst = binary.AppendVarint(dst, p.ref)
+	}
+	dst = appendGroups(dst, vals, p)
+	body := dst[start:]
+	k := binary.PutUvarint(dst[hdr:], uint64(len(body)))
+	copy(dst[hdr+k:], body)
+	dst = dst[:hdr+k+len(body)]
+	return binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[hdr+k:], castagnoli))
+}
+
+// Unmarshal decodes data in the container format written by Marshal.
+// Like DecodeRun, it returns ErrLimit for containers holding more
+// values than MaxRepeat allows for their size. A Reader, which decodes
+// a block at a time, reads those as long as each block is within the
+// limit.
+func Unmarshal(data []byte) ([]int64, error) {
+	p, count, i, err := parseContainer(data)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(decodeLimit(len(data))) {
+		return nil, ErrLimit
+	}
+	// corrupt counts must not cause huge allocations
+	vals := make([]int64, 0, min(count, 1<<20))
+	for uint64(len(vals)) < count {
+		var k int
+		vals, k, err = decodeBlock(vals, data[i:], p, count-uint64(len(vals)))
+		if err != nil {
+			return vals, err
+		}
+		i += k
+	}
+	if uint64(len(vals)) != count {
+		return vals, ErrCorrupt
+	}
+	return vals, checkEnd(data, i)
+}
+
+// checkEnd checks that the blocks of the container in data end at i,
+// followed by nothing but the index, if it has one.
+func checkEnd(data []byte, i int) error {
+	if data[containerFlags]&containerIndexed != 0 {
+		size, err := indexSize(data, int64(len(data)))
+		if err == nil && i+size == len(data) {
+			_, err = parseIndex(data[i:])
+		}
+		if err != nil {
+			return err
+		}
+		i += size
+	}
+	if i != len(data) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// parseContainer parses the container header at the start of data.
+func parseContainer(data []byte) (p preamble, count uint64, size int, err error) {
+	if len(data) < containerHeader {
+		k := min(len(data), len(containerMagic))
+		if string(data[:k]) == containerMagic[:k] {
+			return p, 0, 0, ErrTruncated
+		}
+		return p, 0, 0, ErrCorrupt
+	}
+	if string(data[:len(containerMagic)]) != containerMagic {
+		return p, 0, 0, ErrCorrupt
+	}
+	version := int(data[containerVersion])
+	if version < 1 || version > MaxVersion {
+		return p, 0, 0, ErrVersion
+	}
+	flags := data[containerFlags]
+	if flags&^(flagTransformMask|containerIndexed) != 0 {
+		return p, 0, 0, ErrCorrupt
+	}
+	p.flags = flags&flagTransformMask | uint8(version-1)<<flagVersionShift
+	count, k := binary.Uvarint(data[containerHeader:])
+	if k == 0 {
+		return p, 0, 0, ErrTruncated
+	}
+	if k < 0 {
+		return p, 0, 0, ErrCorrupt
+	}
+	return p, count, containerHeader + k, nil
+}
+
+// parseBlock parses the block at the start of src, checking its data
+// against its checksum, and returns its number of values, its data,
+// and the size of the block. Blocks of more than max values are
+// corrupt.
+func parseBlock(src []byte, max uint64) (n uint64, body []byte, size int, err error) {
+	n, i := binary.Uvarint(src)
+	if i == 0 {
+		return 0, nil, 0, ErrTruncated
+	}
+	if i < 0 || n == 0 || n > max || n > uint64(maxInt) {
+		return 0, nil, 0, ErrCorrupt
+	}
+	bsize, k := binary.Uvarint(src[i:])
+	if k == 0 {
+		return 0, nil, 0, ErrTruncated
+	}
+	if k < 0 {
+		return 0, nil, 0, ErrCorrupt
+	}
+	i += k
+	// the data is followed by 4 bytes of checksum
+	if len(src)-i < 4 || bsize > uint64(len(src)-i-4) {
+		return 0, nil, 0, ErrTruncated
+	}
+	body = src[i : i+int(bsize)]
+	i += int(bsize)
+	if crc32.Checksum(body, castagnoli) != binary.LittleEndian.Uint32(src[i:]) {
+		return 0, nil, 0, ErrChecksum
+	}
+	return n, body, i + 4, nil
+}
+
+// decodeBlock decodes the block at the start of src, appending its
+// values to dst, and returns the size of the block. Blocks of more than
+// max values are corrupt, and blocks of more values than MaxRepeat
+// allows for their size return ErrLimit.
+func decodeBlock(dst []int64, src []byte, p preamble, max uint64) ([]int64, int, error) {
+	n, body, i, err := parseBlock(src, max)
+	if err != nil {
+		return dst, 0, err
+	}
+	if n > uint64(decodeLimit(len(body))) {
+		return dst, 0, ErrLimit
+	}
+	if p.transform() == FrameOfReference {
+		ref, k := binary.Varint(body)
+		if k <= 0 {
+			return dst, 0, ErrCorrupt
+		}
+		p.ref = ref
+		body = body[k:]
+	}
+	start := len(dst)
+	dst, err = decodeGroups(dst, body, p, int(n))
+	if err != nil || uint64(len(dst)-start) != n {
+		return dst[:start], 0, ErrCorrupt
+	}
+	return dst, i, nil
+}
@@ -0,0 +1,125 @@
+package varintrle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+// mustMarshal is Marshal, for options known to be valid.
+func mustMarshal(vals []int64, opt Options) []byte {
+	data, err := Marshal(vals, opt)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestContainer(t *testing.T) {
+	inputs := [][]int64{
+		randRepeatSlice(50),
+		timestamps(10000),
+		{},
+	}
+	opts := []Options{
+		{},
+		{Transform: Delta, BlockSize: 100},
+		{Transform: FrameOfReference, Version: 2},
+		{Transform: DeltaOfDelta, Version: 2, BlockSize: 7},
+	}
+	for _, opt := range opts {
+		for _, vals := range inputs {
+			data := mustMarshal(vals, opt)
+			actual, err := Unmarshal(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vals) != len(actual) || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+				t.Fatalf("%+v: did not match expected output", opt)
+			}
+		}
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	data := mustMarshal(randRepeatSlice(20), Options{Version: 2, BlockSize: 50})
+	for i := 0; i < len(data); i++ {
+		_, err := Unmarshal(data[:i])
+		if err != ErrTruncated {
+			t.Fatalf("truncated to %d bytes: expected ErrTruncated, got %v", i, err)
+		}
+	}
+	damaged := append([]byte(nil), data...)
+	damaged[len(damaged)-5] ^= 0x10
+	if _, err := Unmarshal(damaged); err != ErrChecksum {
+		t.Fatalf("expected ErrChecksum, got %v", err)
+	}
+	damaged = append([]byte(nil), data...)
+	damaged[0] = 'x'
+	if _, err := Unmarshal(damaged); err != ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if _, err := Unmarshal(append(data, 0)); err != ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt for trailing data, got %v", err)
+	}
+}
+
+func TestContainerHostile(t *testing.T) {
+	header := append([]byte(containerMagic), 2, 0)
+	header = binary.AppendUvarint(header, 1)
+
+	// a block size that overflows when added to
+	data := binary.AppendUvarint(append([]byte(nil), header...), 1)
+	data = binary.AppendUvarint(data, 1<<64-3)
+	data = append(data, 0, 0, 0, 0)
+	if _, err := Unmarshal(data); err != ErrTruncated {
+		t.Fatalf("expected ErrTruncated for a huge block, got %v", err)
+	}
+
+	// a block of one value holding a long repeat run must be rejected
+	// before it is decoded
+	body := binary.AppendUvarint([]byte{repeatHeader(0)}, 1<<40)
+	data = binary.AppendUvarint(append([]byte(nil), header...), 1)
+	data = binary.AppendUvarint(data, uint64(len(body)))
+	data = append(data, body...)
+	data = binary.LittleEndian.AppendUint32(data, crc32.Checksum(body, castagnoli))
+	if vals, err := Unmarshal(data); err != ErrCorrupt || cap(vals) > 1<<20 {
+		t.Fatalf("expected ErrCorrupt for a long repeat run, got %v", err)
+	}
+
+	// a header claiming a huge count must not be allocated up front
+	data = hostileContainer(1<<40, 1<<40, AppendRun(nil, []int64{1}))
+	if _, err := Unmarshal(data); err != ErrLimit {
+		t.Fatalf("expected ErrLimit for a huge count, got %v", err)
+	}
+
+	// and neither may a valid block of a long repeat run, 27 bytes in all
+	body = binary.AppendUvarint([]byte{repeatHeader(0)}, 1<<34)
+	data = hostileContainer(1<<34, 1<<34, body)
+	if len(data) != 27 {
+		t.Fatalf("expected a container of 27 bytes, got %d", len(data))
+	}
+	if _, err := Unmarshal(data); err != ErrLimit {
+		t.Fatalf("expected ErrLimit for a long repeat run, got %v", err)
+	}
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		_, err = r.At(0)
+	}
+	if err != ErrLimit {
+		t.Fatalf("Reader: expected ErrLimit for a long repeat run, got %v", err)
+	}
+}
+
+// hostileContainer returns a version 2 container of count values, with
+// one block of n values holding body.
+func hostileContainer(count, n uint64, body []byte) []byte {
+	data := append([]byte(containerMagic), 2, 0)
+	data = binary.AppendUvarint(data, count)
+	data = binary.AppendUvarint(data, n)
+	data = binary.AppendUvarint(data, uint64(len(body)))
+	data = append(data, body...)
+	return binary.LittleEndian.AppendUint32(data, crc32.Checksum(body, castagnoli))
+}
@@ -0,0 +1,273 @@
+package varintrle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"iter"
+	"slices"
+)
+
+// A Decoder reads integer values from an input stream one at a time,
+// without needing to know how many values the stream holds.
+//
+// When the input runs dry, Next returns io.EOF if it stopped between
+// groups and io.ErrUnexpectedEOF if it stopped within one. Either way
+// nothing is lost, so decoding resumes once more data is available, as
+// when following a file that is still being written.
+type Decoder struct {
+	r       *bufio.Reader
+	err     error
+	started bool
+	t       transformer
+	version int
+
+	// values left in the current group
+	n      int
+	width  int
+	repeat bool
+	val    int64
+	packed []uint64
+
+	// in version 3, the current chunk and its values
+	chunk []byte
+	vals  []int64
+}
+
+// NewDecoder returns a new Decoder reading from r. The Decoder buffers
+// its input and may read more than it needs from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Next returns the next value in the stream.
+func (d *Decoder) Next() (int64, error) {
+	if !d.started {
+		if err := d.start(); err != nil {
+			return 0, err
+		}
+	}
+	if d.version == 3 {
+		return d.next3()
+	}
+	if d.n == 0 {
+		if err := d.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+	if d.repeat {
+		d.n--
+		return d.t.undo(d.val), nil
+	}
+	if len(d.packed) > 0 {
+		v := d.packed[0]
+		d.packed = d.packed[1:]
+		d.n--
+		return d.t.undo(unzigzag(v)), nil
+	}
+	if d.width == 0 {
+		d.n--
+		return d.t.undo(0), nil
+	}
+	buf, err := d.r.Peek(d.width)
+	if len(buf) < d.width {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	var val uint64
+	for j := 0; j < d.width; j++ {
+		val |= uint64(buf[j]) << (uint64(j) * 8)
+	}
+	d.r.Discard(d.width)
+	d.n--
+	return d.t.undo(unzigzag(val)), nil
+}
+
+func (d *Decoder) readHeader() error {
+	hdr, err := d.r.Peek(1)
+	if len(hdr) == 0 {
+		return err
+	}
+	b := hdr[0]
+	d.repeat = false
+	if d.version < 2 {
+		d.n, d.width = getnbytes(b)
+		d.r.Discard(1)
+		return nil
+	}
+	n, width, extended := getheader2(b)
+	if !extended {
+		d.n, d.width = n, width
+		d.r.Discard(1)
+		return nil
+	}
+	if isBitGroup(b) {
+		return d.readBitGroup(b)
+	}
+	width, ok := getrepeat(b)
+	if !ok {
+		return ErrCorrupt
+	}
+	buf, err := d.r.Peek(1 + binary.MaxVarintLen64 + width)
+	count, k := binary.Uvarint(buf[1:])
+	if k < 0 || k > 0 && (count == 0 || count > uint64(maxInt)) {
+		return ErrCorrupt
+	}
+	if k == 0 || len(buf)-1-k < width {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	d.n = int(count)
+	d.repeat = true
+	d.val = unzigzag(readPayload(buf, 1+k, width, payloadMask[width]))
+	d.r.Discard(1 + k + width)
+	return nil
+}
+
+func (d *Decoder) readBitGroup(b uint8) error {
+	buf, err := d.r.Peek(2)
+	if len(buf) < 2 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	n, nbits := getbits(b, buf[1])
+	size := 2 + bitsSize(n, nbits)
+	buf, err = d.r.Peek(size)
+	if len(buf) < size {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	d.packed = d.packed[:0]
+	for j := 0; j < n; j++ {
+		d.packed = append(d.packed, readBits(buf[2:], j, nbits))
+	}
+	d.n = n
+	d.r.Discard(size)
+	return nil
+}
+
+func (d *Decoder) next3() (int64, error) {
+	if len(d.vals) == 0 {
+		if err := d.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	v := d.vals[0]
+	d.vals = d.vals[1:]
+	return d.t.undo(v), nil
+}
+
+// readChunk reads and decodes the next chunk of a version 3 stream. The
+// chunk is read into a buffer of its own, so that no more is read from
+// the underlying reader than the chunk itself.
+func (d *Decoder) readChunk() error {
+	for {
+		ctrl, data, size, err := parseChunk(d.chunk)
+		if err == nil {
+			d.vals, err = decodeChunk(d.vals[:0], ctrl, data, maxInt)
+			d.chunk = d.chunk[:0]
+			if len(d.vals) == 0 && err == nil {
+				err = ErrCorrupt
+			}
+			return err
+		}
+		if err != io.ErrUnexpectedEOF {
+			return err
+		}
+		want := 1
+		if size > 0 {
+			want = size - len(d.chunk)
+		}
+		d.chunk = slices.Grow(d.chunk, want)
+		k, err := d.r.Read(d.chunk[len(d.chunk) : len(d.chunk)+want])
+		d.chunk = d.chunk[:len(d.chunk)+k]
+		if err != nil {
+			if err == io.EOF && len(d.chunk) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// start reads the preamble, if the stream has one.
+func (d *Decoder) start() error {
+	buf, err := d.r.Peek(maxPreambleSize)
+	if len(buf) == 0 {
+		return err
+	}
+	p, size, perr := parsePreamble(buf)
+	if perr != nil {
+		if err != nil && err != io.EOF {
+			return err
+		}
+		return perr
+	}
+	if size > 0 {
+		if err := p.check(false); err != nil {
+			return err
+		}
+		if p.version() > MaxVersion {
+			return ErrVersion
+		}
+		d.r.Discard(size)
+		d.t = transformer{p: p}
+		d.version = p.version()
+	}
+	d.started = true
+	return nil
+}
+
+// Read reads up to len(dst) values into dst and returns the number of
+// values read. It returns io.EOF only if no values were read because
+// the stream ended.
+func (d *Decoder) Read(dst []int64) (int, error) {
+	for i := range dst {
+		v, err := d.Next()
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				err = nil
+			}
+			return i, err
+		}
+		dst[i] = v
+	}
+	return len(dst), nil
+}
+
+// All returns an iterator over the remaining values in the stream. The
+// iteration stops at the end of the stream or on the first error, which
+// is then reported by Err.
+func (d *Decoder) All() iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		d.err = nil
+		for {
+			v, err := d.Next()
+			if err != nil {
+				if err != io.EOF {
+					d.err = err
+				}
+				return
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error, if any, that stopped the last iteration
+// returned by All.
+func (d *Decoder) Err() error {
+	return d.err
+}
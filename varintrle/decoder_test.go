@@ -0,0 +1,84 @@
+package varintrle
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	vals := randIntSlice(20)
+	buf := &bytes.Buffer{}
+	err := WriteRun(buf, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()))
+	var actual []int64
+	for v := range dec.All() {
+		actual = append(actual, v)
+	}
+	if dec.Err() != nil {
+		t.Fatal(dec.Err())
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+
+	dec = NewDecoder(bytes.NewReader(buf.Bytes()))
+	actual = make([]int64, len(vals)+1)
+	n, err := dec.Read(actual[:7])
+	if err != nil || n != 7 {
+		t.Fatalf("expected 7 values, got %d: %v", n, err)
+	}
+	m, err := dec.Read(actual[7:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n+m != len(vals) {
+		t.Fatalf("did not read expected number of values")
+	}
+	if !reflect.DeepEqual(vals, actual[:n+m]) {
+		t.Fatalf("did not match expected output")
+	}
+	_, err = dec.Read(actual)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderResume(t *testing.T) {
+	vals := randIntSlice(20)
+	encoded := &bytes.Buffer{}
+	err := WriteRun(encoded, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// feed the stream a few bytes at a time, pausing wherever the
+	// input runs out
+	src := &bytes.Buffer{}
+	dec := NewDecoder(src)
+	var actual []int64
+	for b := encoded.Bytes(); len(b) > 0; {
+		k := 3
+		if k > len(b) {
+			k = len(b)
+		}
+		src.Write(b[:k])
+		b = b[k:]
+		for {
+			v, err := dec.Next()
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, v)
+		}
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+}
@@ -0,0 +1,125 @@
+package varintrle
+
+import (
+	"errors"
+	"io"
+)
+
+var errClosed = errors.New("varintrle: encoder is closed")
+
+// flushSize is how many encoded bytes an Encoder holds before writing
+// them out.
+const flushSize = 4096
+
+// An Encoder writes integer values to an output stream as they are
+// produced, grouping them the same way WriteRun does. Values written
+// between calls to Flush are encoded byte-for-byte the same as one call
+// to WriteRun with all of them.
+type Encoder struct {
+	w       io.Writer
+	buf     []byte
+	err     error
+	opt     Options
+	started bool
+	t       transformer
+	p       packer
+}
+
+// NewEncoder returns a new Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:   w,
+		buf: make([]byte, 0, flushSize),
+	}
+}
+
+// NewEncoderOptions returns a new Encoder writing to w, which encodes
+// values as described by opt. With the FrameOfReference transform, the
+// first value written is used as the reference. If opt.Version is not
+// supported, or opt.Transform is unknown, every method of the Encoder
+// returns ErrVersion or ErrTransform.
+func NewEncoderOptions(w io.Writer, opt Options) *Encoder {
+	e := NewEncoder(w)
+	e.opt = opt
+	e.err = opt.check()
+	return e
+}
+
+// Write encodes a single value.
+func (e *Encoder) Write(v int64) error {
+	if e.err != nil {
+		return e.err
+	}
+	if !e.started {
+		e.start(v)
+	}
+	e.buf = e.p.add(e.buf, zigzag(e.t.apply(v)))
+	if len(e.buf) >= flushSize {
+		return e.writeBuf(e.p.rw.pending(e.buf))
+	}
+	return nil
+}
+
+// WriteSlice encodes all of vals.
+func (e *Encoder) WriteSlice(vals []int64) error {
+	for _, v := range vals {
+		if err := e.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush ends the current group and writes any buffered data to the
+// underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = e.p.end(e.buf)
+	return e.writeBuf(len(e.buf))
+}
+
+// Close flushes the Encoder. Further writes return an error. Close
+// does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.err == errClosed {
+		return nil
+	}
+	err := e.Flush()
+	if err == nil {
+		e.err = errClosed
+	}
+	return err
+}
+
+// start writes the preamble, given the first value of the stream.
+func (e *Encoder) start(first int64) {
+	e.started = true
+	if e.opt.plain() {
+		return
+	}
+	p := makePreamble(e.opt)
+	e.p = newPacker(p.version())
+	if e.opt.Transform == FrameOfReference {
+		p.ref = first
+	}
+	e.buf = appendPreamble(e.buf, p)
+	e.t = transformer{p: p}
+}
+
+// writeBuf writes the first n bytes of the buffer, which must be
+// complete groups.
+func (e *Encoder) writeBuf(n int) error {
+	if n == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.buf[:n])
+	k := copy(e.buf, e.buf[n:])
+	e.buf = e.buf[:k]
+	e.p.rw.hdr -= n
+	if err != nil {
+		e.err = err
+	}
+	return err
+}
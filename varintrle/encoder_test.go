@@ -0,0 +1,72 @@
+package varintrle
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoderMatchesWriteRun(t *testing.T) {
+	vals := randIntSlice(50)
+	expected := &bytes.Buffer{}
+	err := WriteRun(expected, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	for _, v := range vals[:len(vals)/2] {
+		err = enc.Write(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = enc.WriteSlice(vals[len(vals)/2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+		t.Fatalf("did not match WriteRun output")
+	}
+	if enc.Write(1) == nil {
+		t.Fatalf("expected error writing to closed encoder")
+	}
+}
+
+func TestEncoderFlush(t *testing.T) {
+	vals := randIntSlice(20)
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	for i, v := range vals {
+		err := enc.Write(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%7 == 0 {
+			err = enc.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err := enc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make([]int64, len(vals))
+	n, err := ReadRun(actual, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(vals) {
+		t.Fatalf("did not read expected number of values")
+	}
+	for i := range vals {
+		if vals[i] != actual[i] {
+			t.Fatalf("did not match expected output at %d", i)
+		}
+	}
+}
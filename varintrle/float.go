@@ -0,0 +1,384 @@
+package varintrle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Streams of floats XOR each value with the one before it, as in
+// Facebook's Gorilla, so that values which change little, or not at
+// all, leave few bits set. The bits left are stored as the number of
+// trailing zeros, and the significant bits above them, each as a column
+// of unsigned values in version 2 groups. The leading zeros are left
+// out, as the width of the group already accounts for them. A run of
+// the same value has no bits set, and so costs only a repeat run in
+// each column.
+//
+// A float stream has a preamble marking it as such, followed by chunks:
+//
+//	n        uvarint number of values
+//	zsize    uvarint size of the trailing zero column
+//	ssize    uvarint size of the significant bits column
+//	zeros    trailing zeros column
+//	bits     significant bits column
+const maxFloatChunk = 1024
+
+// ErrNotFloat is returned by DecodeFloats and a FloatDecoder for
+// streams that do not hold floats.
+var ErrNotFloat = errors.New("varintrle: stream does not hold floats")
+
+// floatPacker XORs float values and packs them into chunks.
+type floatPacker struct {
+	prev  uint64
+	n     int
+	zeros []byte
+	sig   []byte
+	zp    packer
+	sp    packer
+}
+
+func newFloatPacker() floatPacker {
+	return floatPacker{
+		zp: newPacker(2),
+		sp: newPacker(2),
+	}
+}
+
+func (f *floatPacker) add(dst []byte, v float64) []byte {
+	b := math.Float64bits(v)
+	x := b ^ f.prev
+	f.prev = b
+	tz := 0
+	if x != 0 {
+		tz = bits.TrailingZeros64(x)
+	}
+	f.zeros = f.zp.add(f.zeros, uint64(tz))
+	f.sig = f.sp.add(f.sig, x>>uint(tz))
+	f.n++
+	if f.n == maxFloatChunk {
+		dst = f.end(dst)
+	}
+	return dst
+}
+
+func (f *floatPacker) end(dst []byte) []byte {
+	if f.n == 0 {
+		return dst
+	}
+	f.zeros = f.zp.end(f.zeros)
+	f.sig = f.sp.end(f.sig)
+	dst = binary.AppendUvarint(dst, uint64(f.n))
+	dst = binary.AppendUvarint(dst, uint64(len(f.zeros)))
+	dst = binary.AppendUvarint(dst, uint64(len(f.sig)))
+	dst = append(dst, f.zeros...)
+	dst = append(dst, f.sig...)
+	f.n = 0
+	f.zeros = f.zeros[:0]
+	f.sig = f.sig[:0]
+	return dst
+}
+
+// AppendFloats appends the encoding of vals to dst and returns the
+// extended buffer.
+func AppendFloats(dst []byte, vals []float64) []byte {
+	dst = appendPreamble(dst, preamble{flags: flagFloat})
+	f := newFloatPacker()
+	for _, v := range vals {
+		dst = f.add(dst, v)
+	}
+	return f.end(dst)
+}
+
+// parseFloatChunk returns the columns of the chunk at the start of src,
+// and its size. Like parseChunk, if src holds only part of the chunk,
+// but all of its size fields, the size is returned along with
+// io.ErrUnexpectedEOF.
+func parseFloatChunk(src []byte) (n int, zeros, sig []byte, size int, err error) {
+	var fields [3]uint64
+	i := 0
+	for j := range fields {
+		x, k := binary.Uvarint(src[i:])
+		if k == 0 {
+			return 0, nil, nil, 0, io.ErrUnexpectedEOF
+		}
+		if k < 0 || x > maxFloatChunk*(binary.MaxVarintLen64+8) {
+			return 0, nil, nil, 0, ErrCorrupt
+		}
+		fields[j] = x
+		i += k
+	}
+	if fields[0] == 0 || fields[0] > maxFloatChunk {
+		return 0, nil, nil, 0, ErrCorrupt
+	}
+	size = i + int(fields[1]+fields[2])
+	if len(src) < size {
+		return 0, nil, nil, size, io.ErrUnexpectedEOF
+	}
+	zeros = src[i : i+int(fields[1])]
+	sig = src[i+int(fields[1]) : size]
+	return int(fields[0]), zeros, sig, size, nil
+}
+
+// decodeFloatChunk decodes the values of a chunk and appends them to
+// dst, given the bits of the value before them.
+func decodeFloatChunk(dst []float64, n int, zeros, sig []byte, prev uint64) ([]float64, uint64, error) {
+	zv := newValues(zeros, 2)
+	sv := newValues(sig, 2)
+	for ; n > 0; n-- {
+		tz, ok := zv.next()
+		if !ok {
+			return dst, prev, chunkErr(zv.s.err)
+		}
+		s, ok := sv.next()
+		if !ok {
+			return dst, prev, chunkErr(sv.s.err)
+		}
+		if tz >= 64 || s<<tz>>tz != s {
+			return dst, prev, ErrCorrupt
+		}
+		prev ^= s << tz
+		dst = append(dst, math.Float64frombits(prev))
+	}
+	if _, ok := zv.next(); ok || zv.s.err != nil {
+		return dst, prev, ErrCorrupt
+	}
+	if _, ok := sv.next(); ok || sv.s.err != nil {
+		return dst, prev, ErrCorrupt
+	}
+	return dst, prev, nil
+}
+
+// chunkErr returns the error for a column that ends early. Columns are
+// sized by their chunk, so it is never just the input running out.
+func chunkErr(err error) error {
+	if err == nil || err == io.ErrUnexpectedEOF {
+		return ErrCorrupt
+	}
+	return err
+}
+
+func checkFloatPreamble(p preamble, size int) error {
+	if size == 0 || p.flags != flagFloat {
+		return ErrNotFloat
+	}
+	return nil
+}
+
+// DecodeFloats decodes all values in src, written by AppendFloats or a
+// FloatEncoder, and appends them to dst, returning the extended slice.
+// Streams of integers return ErrNotFloat.
+func DecodeFloats(dst []float64, src []byte) ([]float64, error) {
+	if len(src) == 0 {
+		return dst, nil
+	}
+	p, i, err := parsePreamble(src)
+	if err == nil {
+		err = checkFloatPreamble(p, i)
+	}
+	if err != nil {
+		return dst, err
+	}
+	prev := uint64(0)
+	for i < len(src) {
+		n, zeros, sig, size, err := parseFloatChunk(src[i:])
+		if err != nil {
+			return dst, err
+		}
+		dst, prev, err = decodeFloatChunk(dst, n, zeros, sig, prev)
+		if err != nil {
+			return dst, err
+		}
+		i += size
+	}
+	return dst, nil
+}
+
+// A FloatEncoder writes float values to an output stream as they are
+// produced. Values are written out in chunks of up to 1024 values, or
+// on Flush.
+type FloatEncoder struct {
+	w       io.Writer
+	buf     []byte
+	err     error
+	f       floatPacker
+	started bool
+}
+
+// NewFloatEncoder returns a new FloatEncoder writing to w.
+func NewFloatEncoder(w io.Writer) *FloatEncoder {
+	return &FloatEncoder{
+		w: w,
+		f: newFloatPacker(),
+	}
+}
+
+// Write encodes a single value.
+func (e *FloatEncoder) Write(v float64) error {
+	if e.err != nil {
+		return e.err
+	}
+	if !e.started {
+		e.started = true
+		e.buf = appendPreamble(e.buf, preamble{flags: flagFloat})
+	}
+	e.buf = e.f.add(e.buf, v)
+	if len(e.buf) >= flushSize {
+		return e.writeBuf()
+	}
+	return nil
+}
+
+// WriteSlice encodes all of vals.
+func (e *FloatEncoder) WriteSlice(vals []float64) error {
+	for _, v := range vals {
+		if err := e.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush ends the current chunk and writes any buffered data to the
+// underlying writer.
+func (e *FloatEncoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = e.f.end(e.buf)
+	return e.writeBuf()
+}
+
+// Close flushes the FloatEncoder. Further writes return an error. Close
+// does not close the underlying writer.
+func (e *FloatEncoder) Close() error {
+	if e.err == errClosed {
+		return nil
+	}
+	err := e.Flush()
+	if err == nil {
+		e.err = errClosed
+	}
+	return err
+}
+
+func (e *FloatEncoder) writeBuf() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	if err != nil {
+		e.err = err
+	}
+	return err
+}
+
+// A FloatDecoder reads float values from an input stream one at a
+// time. Like a Decoder, it can resume after the input runs dry, in this
+// case between chunks.
+type FloatDecoder struct {
+	r       *bufio.Reader
+	err     error
+	started bool
+	prev    uint64
+
+	chunk []byte
+	vals  []float64
+}
+
+// NewFloatDecoder returns a new FloatDecoder reading from r.
+func NewFloatDecoder(r io.Reader) *FloatDecoder {
+	return &FloatDecoder{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Next returns the next value in the stream.
+func (d *FloatDecoder) Next() (float64, error) {
+	if !d.started {
+		buf, err := d.r.Peek(preambleSize)
+		if len(buf) < preambleSize {
+			if len(buf) > 0 && (err == nil || err == io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		p, size, err := parsePreamble(buf)
+		if err == nil {
+			err = checkFloatPreamble(p, size)
+		}
+		if err != nil {
+			return 0, err
+		}
+		d.r.Discard(size)
+		d.started = true
+	}
+	if len(d.vals) == 0 {
+		if err := d.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	v := d.vals[0]
+	d.vals = d.vals[1:]
+	return v, nil
+}
+
+func (d *FloatDecoder) readChunk() error {
+	for {
+		n, zeros, sig, size, err := parseFloatChunk(d.chunk)
+		if err == nil {
+			d.vals, d.prev, err = decodeFloatChunk(d.vals[:0], n, zeros, sig, d.prev)
+			d.chunk = d.chunk[:0]
+			return err
+		}
+		if err != io.ErrUnexpectedEOF {
+			return err
+		}
+		want := 1
+		if size > 0 {
+			want = size - len(d.chunk)
+		}
+		d.chunk = slices.Grow(d.chunk, want)
+		k, err := d.r.Read(d.chunk[len(d.chunk) : len(d.chunk)+want])
+		d.chunk = d.chunk[:len(d.chunk)+k]
+		if err != nil {
+			if err == io.EOF && len(d.chunk) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// All returns an iterator over the remaining values in the stream. The
+// iteration stops at the end of the stream or on the first error, which
+// is then reported by Err.
+func (d *FloatDecoder) All() iter.Seq[float64] {
+	return func(yield func(float64) bool) {
+		d.err = nil
+		for {
+			v, err := d.Next()
+			if err != nil {
+				if err != io.EOF {
+					d.err = err
+				}
+				return
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error, if any, that stopped the last iteration
+// returned by All.
+func (d *FloatDecoder) Err() error {
+	return d.err
+}
@@ -0,0 +1,109 @@
+package varintrle
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randFloatSlice(n int) []float64 {
+	vals := make([]float64, n)
+	v := 20.0
+	for i := range vals {
+		switch rand.Intn(4) {
+		case 0:
+			v = rand.NormFloat64() * 1e6
+		case 1:
+			v += 0.5
+		}
+		vals[i] = v
+	}
+	return vals
+}
+
+func sameFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFloats(t *testing.T) {
+	vals := append(randFloatSlice(3000), math.NaN(), math.Inf(-1), math.Copysign(0, -1), 0)
+	buf := AppendFloats(nil, vals)
+	actual, err := DecodeFloats(nil, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameFloats(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+	if _, err := DecodeRun(nil, buf); err != ErrFloat {
+		t.Fatalf("expected ErrFloat, got %v", err)
+	}
+	if _, err := DecodeFloats(nil, AppendRun(nil, []int64{1})); err != ErrNotFloat {
+		t.Fatalf("expected ErrNotFloat, got %v", err)
+	}
+
+	same := make([]float64, 10000)
+	for i := range same {
+		same[i] = 98.6
+	}
+	buf = AppendFloats(nil, same)
+	if len(buf) > len(same)/50 {
+		t.Fatalf("expected a run of the same float to be small, got %d bytes", len(buf))
+	}
+	actual, err = DecodeFloats(actual[:0], buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameFloats(same, actual) {
+		t.Fatalf("did not match expected output")
+	}
+}
+
+func TestFloatEncoder(t *testing.T) {
+	vals := randFloatSlice(3000)
+	w := &bytes.Buffer{}
+	enc := NewFloatEncoder(w)
+	for i, v := range vals {
+		err := enc.Write(v)
+		if err == nil && i%500 == 0 {
+			err = enc.Flush()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	src := &bytes.Buffer{}
+	dec := NewFloatDecoder(src)
+	var actual []float64
+	for b := w.Bytes(); len(b) > 0; {
+		k := min(rand.Intn(700)+1, len(b))
+		src.Write(b[:k])
+		b = b[k:]
+		for {
+			v, err := dec.Next()
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, v)
+		}
+	}
+	if !sameFloats(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+}
@@ -0,0 +1,141 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Streams written with options that change how values must be decoded
+// begin with a preamble: a group of one 1 byte value holding zero,
+// which is never written otherwise since zero always packs into 0
+// bytes, followed by a byte of flags, and then a varint frame of
+// reference if the FrameOfReference transform is used. Plain streams
+// have no preamble, so they read the same as they always have.
+const (
+	preambleHeader  = 0x01 // nbytes(1, 1)
+	preambleSize    = 3
+	maxPreambleSize = preambleSize + binary.MaxVarintLen64
+)
+
+const (
+	flagUnsigned = 1 << iota
+
+	// the transform takes up two bits
+	flagTransformShift = iota
+	flagTransformMask  = 3 << flagTransformShift
+
+	// streams of floats, rather than integers
+	flagFloat = 1 << 3
+
+	// and the format version, less one, the top four
+	flagVersionShift = 4
+)
+
+// MaxVersion is the latest version of the format.
+const MaxVersion = 3
+
+var (
+	// ErrSignedness is returned when reading a stream of unsigned
+	// values as signed values, or vice versa.
+	ErrSignedness = errors.New("varintrle: stream has the wrong signedness")
+
+	// ErrVersion is returned when reading a stream written in a newer
+	// version of the format than is supported.
+	ErrVersion = errors.New("varintrle: unsupported format version")
+
+	// ErrTransform is returned when encoding with a Transform which is
+	// not one of those defined.
+	ErrTransform = errors.New("varintrle: unknown transform")
+
+	// ErrFloat is returned when reading a stream of floats as integers.
+	ErrFloat = errors.New("varintrle: stream holds floats, not integers")
+
+	// ErrNotPlain is returned by ReadRun and ReadRunFromBytes for
+	// streams written with options they cannot undo, such as a
+	// Transform. DecodeRun and a Decoder read all streams.
+	ErrNotPlain = errors.New("varintrle: stream has options that need DecodeRun or a Decoder to read")
+)
+
+// preamble describes how the values of a stream were encoded.
+type preamble struct {
+	flags uint8
+	ref   int64
+}
+
+// makePreamble returns the preamble for opt, which must have been
+// checked.
+func makePreamble(opt Options) preamble {
+	flags := uint8(opt.Transform) << flagTransformShift
+	if opt.Version > 1 {
+		flags |= uint8(opt.Version-1) << flagVersionShift
+	}
+	return preamble{
+		flags: flags,
+	}
+}
+
+func (p preamble) version() int {
+	return int(p.flags>>flagVersionShift) + 1
+}
+
+func (p preamble) transform() Transform {
+	return Transform(p.flags&flagTransformMask) >> flagTransformShift
+}
+
+func appendPreamble(dst []byte, p preamble) []byte {
+	dst = append(dst, preambleHeader, 0, p.flags)
+	if p.transform() == FrameOfReference {
+		dst = binary.AppendVarint(dst, p.ref)
+	}
+	return dst
+}
+
+// parsePreamble returns the preamble at the start of src and its size,
+// or a size of 0 if src has no preamble.
+func parsePreamble(src []byte) (p preamble, size int, err error) {
+	if len(src) < 2 || src[0] != preambleHeader || src[1] != 0 {
+		if len(src) == 1 && src[0] == preambleHeader {
+			return p, 0, io.ErrUnexpectedEOF
+		}
+		return p, 0, nil
+	}
+	if len(src) < preambleSize {
+		return p, 0, io.ErrUnexpectedEOF
+	}
+	p.flags = src[2]
+	size = preambleSize
+	if p.transform() == FrameOfReference {
+		ref, n := binary.Varint(src[size:])
+		if n <= 0 {
+			return p, 0, io.ErrUnexpectedEOF
+		}
+		p.ref = ref
+		size += n
+	}
+	return p, size, nil
+}
+
+// check returns ErrSignedness unless p describes a stream of the given
+// signedness.
+func (p preamble) check(unsigned bool) error {
+	if p.flags&flagFloat != 0 {
+		return ErrFloat
+	}
+	if (p.flags&flagUnsigned != 0) != unsigned {
+		return ErrSignedness
+	}
+	return nil
+}
+
+// checkPlain is like check, but also fails for streams that need more
+// than the plain group decoding of ReadRun.
+func (p preamble) checkPlain(unsigned bool) error {
+	if err := p.check(unsigned); err != nil {
+		return err
+	}
+	if p.flags&^flagUnsigned != 0 {
+		return ErrNotPlain
+	}
+	return nil
+}
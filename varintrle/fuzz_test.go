@@ -0,0 +1,122 @@
+package varintrle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fuzzOptions picks options for a fuzzed input from its first byte.
+func fuzzOptions(b byte) Options {
+	return Options{
+		Transform: Transform(b & 3),
+		Version:   int(b>>2)%MaxVersion + 1,
+		BlockSize: int(b>>4) + 1,
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(append([]byte{0x0a}, make([]byte, 64)...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		opt := fuzzOptions(data[0])
+		var vals []int64
+		for data = data[1:]; len(data) >= 8; data = data[8:] {
+			vals = append(vals, int64(binary.LittleEndian.Uint64(data)))
+		}
+		// small values too, which make for more interesting groups
+		for _, b := range data {
+			vals = append(vals, int64(int8(b)))
+		}
+
+		buf := mustEncode(vals, opt)
+		actual, err := DecodeStrict(nil, buf, len(vals))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("DecodeStrict did not match input")
+		}
+		actual, err = DecodeRun(actual[:0], buf)
+		if err != nil || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("DecodeRun did not match input: %v", err)
+		}
+		dec := NewDecoder(bytes.NewReader(buf))
+		actual = actual[:0]
+		for v := range dec.All() {
+			actual = append(actual, v)
+		}
+		if dec.Err() != nil || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("Decoder did not match input: %v", dec.Err())
+		}
+		actual, err = Unmarshal(mustMarshal(vals, opt))
+		if err != nil || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("Unmarshal did not match input: %v", err)
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	vals := randRepeatSlice(10)
+	for b := 0; b < 16; b++ {
+		opt := fuzzOptions(byte(b))
+		f.Add(mustEncode(vals, opt))
+		f.Add(mustMarshal(vals, opt))
+	}
+	f.Add(AppendFloats(nil, []float64{1, 1.5, 2}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		const max = 1 << 16
+		strict, serr := DecodeStrict(nil, data, max)
+		if serr != nil {
+			var de *DecodeError
+			if !errors.As(serr, &de) || de.Offset < 0 || de.Offset > len(data) {
+				t.Fatalf("DecodeStrict returned %v", serr)
+			}
+		}
+
+		// the decoders which return a slice bound it by their input
+		loose, err := DecodeRun(nil, data)
+		if serr == nil && (err != nil || !reflect.DeepEqual(strict, loose)) {
+			t.Fatalf("DecodeRun did not match DecodeStrict: %v", err)
+		}
+		parallel, perr := DecodeParallel(data, 2)
+		if perr != err || len(parallel) != len(loose) || len(loose) > 0 && !reflect.DeepEqual(loose, parallel) {
+			t.Fatalf("DecodeParallel did not match DecodeRun: %v, %v", perr, err)
+		}
+		if s, serr := Aggregate(data); err == nil && serr == nil && s.Count != len(loose) {
+			t.Fatalf("Aggregate counted %d values, DecodeRun %d", s.Count, len(loose))
+		}
+		DecodeInts[int32](nil, data)
+		DecodeAuto(nil, data)
+
+		// the Decoder streams repeat runs without expanding them, but
+		// may still yield a great many values
+		dec := NewDecoder(bytes.NewReader(data))
+		n := 0
+		for range dec.All() {
+			if n++; n > max {
+				break
+			}
+		}
+
+		// these are bounded by their inputs
+		out := make([]int64, 64)
+		ReadRunFromBytes(out, data)
+		ReadRun(out, bytes.NewReader(data))
+		DecodeFloats(nil, data)
+		Inspect(data, func(GroupInfo) bool { return true })
+
+		InspectContainer(data, func(BlockInfo) bool { return true })
+		if vals, err := Unmarshal(data); err == nil {
+			if r, err := NewReader(bytes.NewReader(data), int64(len(data))); err == nil && r.Len() != len(vals) {
+				t.Fatalf("Reader found %d values, Unmarshal %d", r.Len(), len(vals))
+			}
+		}
+		NewReader(bytes.NewReader(data), int64(len(data)))
+	})
+}
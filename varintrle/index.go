@@ -0,0 +1,286 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// A container may end with an index of its blocks, which records the
+// offset of each block and the position of its first value:
+//
+//	n        uvarint number of blocks
+//	blocks   uvarint offset and first position of each block, each as
+//	         the difference from the block before it
+//	size     size of the index up to here, 4 bytes little endian
+//	crc      CRC-32C of the index up to size, little endian
+const (
+	containerIndexed = 1 << 7
+	indexTrailer     = 8
+)
+
+type blockInfo struct {
+	offset int64
+	first  int64
+}
+
+func appendIndex(dst []byte, index []blockInfo) []byte {
+	start := len(dst)
+	dst = binary.AppendUvarint(dst, uint64(len(index)))
+	var prev blockInfo
+	for _, b := range index {
+		dst = binary.AppendUvarint(dst, uint64(b.offset-prev.offset))
+		dst = binary.AppendUvarint(dst, uint64(b.first-prev.first))
+		prev = b
+	}
+	size := len(dst) - start
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(size))
+	return binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[start:start+size], castagnoli))
+}
+
+// indexSize returns the size of the index, including its trailer, at
+// the end of a container of the given size. data must end with at least
+// the trailer.
+func indexSize(data []byte, size int64) (int, error) {
+	if len(data) < indexTrailer {
+		return 0, ErrTruncated
+	}
+	isize := int64(binary.LittleEndian.Uint32(data[len(data)-indexTrailer:]))
+	if isize > size-indexTrailer {
+		return 0, ErrCorrupt
+	}
+	return int(isize) + indexTrailer, nil
+}
+
+func parseIndex(buf []byte) ([]blockInfo, error) {
+	size := len(buf) - indexTrailer
+	if binary.LittleEndian.Uint32(buf[size+4:]) != crc32.Checksum(buf[:size], castagnoli) {
+		return nil, ErrChecksum
+	}
+	buf = buf[:size]
+	n, i := binary.Uvarint(buf)
+	if i <= 0 || n > uint64(len(buf)) {
+		return nil, ErrCorrupt
+	}
+	index := make([]blockInfo, n)
+	var prev blockInfo
+	for j := range index {
+		offset, k := binary.Uvarint(buf[i:])
+		if k <= 0 {
+			return nil, ErrCorrupt
+		}
+		i += k
+		first, k := binary.Uvarint(buf[i:])
+		if k <= 0 {
+			return nil, ErrCorrupt
+		}
+		i += k
+		prev.offset += int64(offset)
+		prev.first += int64(first)
+		index[j] = prev
+	}
+	if i != len(buf) {
+		return nil, ErrCorrupt
+	}
+	return index, nil
+}
+
+var errRange = errors.New("varintrle: index out of range")
+
+// A Reader reads values at any position of a container written by
+// Marshal, decoding only the blocks it needs. Containers written with
+// the Index option are opened by reading their index. Others are opened
+// by reading the headers of all their blocks.
+type Reader struct {
+	r     io.ReaderAt
+	p     preamble
+	count int64
+	// blocks, and one past the last block
+	index []blockInfo
+
+	// the last block read, and its values
+	block int
+	vals  []int64
+	buf   []byte
+}
+
+// NewReader returns a Reader for the container of the given size in r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	hdr := make([]byte, containerHeader+binary.MaxVarintLen64)
+	n, err := r.ReadAt(hdr[:min(int64(len(hdr)), size)], 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	p, count, start, err := parseContainer(hdr[:n])
+	if err != nil {
+		return nil, err
+	}
+	rd := &Reader{
+		r:     r,
+		p:     p,
+		count: int64(count),
+		block: -1,
+	}
+	end := size
+	if hdr[containerFlags]&containerIndexed != 0 {
+		end, err = rd.readIndex(size)
+	} else {
+		err = rd.scanIndex(int64(start), size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	first, last := rd.index[0], rd.index[len(rd.index)-1]
+	if first.first != 0 || first.offset < int64(start) || last.first != rd.count || last.offset != end {
+		return nil, ErrCorrupt
+	}
+	return rd, nil
+}
+
+// readIndex reads the index at the end of the container, and returns
+// its offset.
+func (rd *Reader) readIndex(size int64) (int64, error) {
+	if size < indexTrailer {
+		return 0, ErrTruncated
+	}
+	var trailer [indexTrailer]byte
+	_, err := rd.r.ReadAt(trailer[:], size-indexTrailer)
+	if err != nil {
+		return 0, err
+	}
+	isize, err := indexSize(trailer[:], size)
+	if err != nil {
+		return 0, err
+	}
+	offset := size - int64(isize)
+	if offset < containerHeader {
+		return 0, ErrCorrupt
+	}
+	buf := make([]byte, isize)
+	_, err = rd.r.ReadAt(buf, offset)
+	if err != nil {
+		return 0, err
+	}
+	rd.index, err = parseIndex(buf)
+	if err != nil {
+		return 0, err
+	}
+	rd.index = append(rd.index, blockInfo{offset: offset, first: rd.count})
+	return offset, nil
+}
+
+// scanIndex builds the index by reading the header of each block.
+func (rd *Reader) scanIndex(offset, size int64) error {
+	var hdr [2 * binary.MaxVarintLen64]byte
+	first := int64(0)
+	for first < rd.count {
+		k, err := rd.r.ReadAt(hdr[:min(int64(len(hdr)), size-offset)], offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		n, i := binary.Uvarint(hdr[:k])
+		if i == 0 {
+			return ErrTruncated
+		}
+		if i < 0 || n == 0 {
+			return ErrCorrupt
+		}
+		bsize, j := binary.Uvarint(hdr[i:k])
+		if j == 0 {
+			return ErrTruncated
+		}
+		if j < 0 || bsize > uint64(size) {
+			return ErrCorrupt
+		}
+		rd.index = append(rd.index, blockInfo{offset: offset, first: first})
+		offset += int64(i+j) + int64(bsize) + 4
+		first += int64(n)
+		if offset > size {
+			return ErrTruncated
+		}
+	}
+	rd.index = append(rd.index, blockInfo{offset: offset, first: first})
+	return nil
+}
+
+// Len returns the number of values in the container.
+func (rd *Reader) Len() int {
+	return int(rd.count)
+}
+
+// At returns the value at position i.
+func (rd *Reader) At(i int) (int64, error) {
+	if i < 0 || int64(i) >= rd.count {
+		return 0, errRange
+	}
+	b := rd.find(int64(i))
+	if err := rd.load(b); err != nil {
+		return 0, err
+	}
+	return rd.vals[int64(i)-rd.index[b].first], nil
+}
+
+// Range appends the values at positions i up to j to dst.
+func (rd *Reader) Range(i, j int, dst []int64) ([]int64, error) {
+	if i < 0 || j < i || int64(j) > rd.count {
+		return dst, errRange
+	}
+	for b := rd.find(int64(i)); i < j; b++ {
+		if err := rd.load(b); err != nil {
+			return dst, err
+		}
+		first := rd.index[b].first
+		end := min(int64(j), rd.index[b+1].first)
+		dst = append(dst, rd.vals[int64(i)-first:end-first]...)
+		i = int(end)
+	}
+	return dst, nil
+}
+
+// find returns the block holding the value at position i.
+func (rd *Reader) find(i int64) int {
+	lo, hi := 0, len(rd.index)-1
+	for lo < hi {
+		m := int(uint(lo+hi+1) >> 1)
+		if rd.index[m].first <= i {
+			lo = m
+		} else {
+			hi = m - 1
+		}
+	}
+	return lo
+}
+
+// load decodes block b, unless it was the last block loaded.
+func (rd *Reader) load(b int) error {
+	if b == rd.block {
+		return nil
+	}
+	rd.block = -1
+	size := rd.index[b+1].offset - rd.index[b].offset
+	if size <= 0 {
+		return ErrCorrupt
+	}
+	if int64(cap(rd.buf)) < size {
+		rd.buf = make([]byte, size)
+	}
+	buf := rd.buf[:size]
+	_, err := rd.r.ReadAt(buf, rd.index[b].offset)
+	if err != nil {
+		if err == io.EOF {
+			err = ErrTruncated
+		}
+		return err
+	}
+	vals, k, err := decodeBlock(rd.vals[:0], buf, rd.p, uint64(rd.index[b+1].first-rd.index[b].first))
+	rd.vals = vals
+	if err != nil {
+		return err
+	}
+	if int64(k) != size || int64(len(vals)) != rd.index[b+1].first-rd.index[b].first {
+		return ErrCorrupt
+	}
+	rd.block = b
+	return nil
+}
@@ -0,0 +1,62 @@
+package varintrle
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	vals := randRepeatSlice(100)
+	for _, opt := range []Options{
+		{BlockSize: 64, Index: true},
+		{BlockSize: 100, Transform: Delta, Version: 2},
+		{Index: true},
+	} {
+		data := mustMarshal(vals, opt)
+		actual, err := Unmarshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("%+v: Unmarshal did not match expected output", opt)
+		}
+		rd, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rd.Len() != len(vals) {
+			t.Fatalf("expected Len %d, got %d", len(vals), rd.Len())
+		}
+		for k := 0; k < 100; k++ {
+			i := rand.Intn(len(vals))
+			v, err := rd.At(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != vals[i] {
+				t.Fatalf("%+v: At(%d) = %d, expected %d", opt, i, v, vals[i])
+			}
+			j := i + rand.Intn(len(vals)-i+1)
+			got, err := rd.Range(i, j, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != j-i || j > i && !reflect.DeepEqual(vals[i:j], got) {
+				t.Fatalf("%+v: Range(%d, %d) did not match expected output", opt, i, j)
+			}
+		}
+		if _, err := rd.At(len(vals)); err == nil {
+			t.Fatalf("expected error reading past the end")
+		}
+	}
+}
+
+func TestReaderDamagedIndex(t *testing.T) {
+	data := mustMarshal(randIntSlice(100), Options{BlockSize: 64, Index: true})
+	data[len(data)-indexTrailer-1] ^= 1
+	if _, err := NewReader(bytes.NewReader(data), int64(len(data))); err != ErrChecksum {
+		t.Fatalf("expected ErrChecksum, got %v", err)
+	}
+}
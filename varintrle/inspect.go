@@ -0,0 +1,157 @@
+package varintrle
+
+import "encoding/binary"
+
+// A StreamInfo describes how a stream was encoded, as read from its
+// preamble.
+type StreamInfo struct {
+	Version   int
+	Transform Transform
+	Unsigned  bool
+	// Ref is the reference value of the FrameOfReference transform.
+	Ref int64
+	// Size is the size of the preamble in bytes.
+	Size int
+}
+
+// A GroupInfo describes a group of values in a stream.
+type GroupInfo struct {
+	// Offset is the offset of the group's header in the stream.
+	Offset int
+	// Count is the number of values in the group.
+	Count int
+	// Width is the size of each value in bytes, or 0 if the values
+	// are bit-packed or all zero.
+	Width int
+	// Bits is the size of each value in bits, if the values are
+	// bit-packed.
+	Bits int
+	// Repeat is set if the group is a repeat run of a single value.
+	Repeat bool
+	// Values holds the values of the group as they were stored, before
+	// any transform is undone. A repeat run holds its value once.
+	Values []int64
+}
+
+// Inspect reads the preamble of the stream in src and then calls fn
+// for each of its groups, stopping early if fn returns false. It is
+// meant for tools and debugging, rather than for reading values.
+func Inspect(src []byte, fn func(GroupInfo) bool) (StreamInfo, error) {
+	p, i, err := parsePreamble(src)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	info := StreamInfo{
+		Version:   p.version(),
+		Transform: p.transform(),
+		Unsigned:  p.flags&flagUnsigned != 0,
+		Ref:       p.ref,
+		Size:      i,
+	}
+	if p.flags&flagFloat != 0 {
+		return info, ErrFloat
+	}
+	if info.Version > MaxVersion {
+		return info, ErrVersion
+	}
+	_, err = inspectGroups(src[i:], i, p, fn)
+	return info, err
+}
+
+// inspectGroups calls fn for each group in src, which was written as
+// described by p and starts at offset off, and reports whether fn
+// stopped early.
+func inspectGroups(src []byte, off int, p preamble, fn func(GroupInfo) bool) (bool, error) {
+	unsigned := p.flags&flagUnsigned != 0
+	s := newScanner(src, p.version())
+	for {
+		g, ok := s.next()
+		if !ok {
+			return false, s.err
+		}
+		gi := GroupInfo{
+			Offset: off + s.hdr,
+			Count:  g.n,
+			Width:  g.bytes,
+			Bits:   g.bits,
+			Repeat: g.repeat,
+		}
+		n := g.n
+		if g.repeat {
+			n = 1
+		}
+		for j := 0; j < n; j++ {
+			v := g.value(j)
+			if unsigned {
+				gi.Values = append(gi.Values, int64(v))
+			} else {
+				gi.Values = append(gi.Values, unzigzag(v))
+			}
+		}
+		if !fn(gi) {
+			return true, nil
+		}
+	}
+}
+
+// A BlockInfo describes a block of a container.
+type BlockInfo struct {
+	// Offset is the offset of the block in the container.
+	Offset int
+	// Count is the number of values in the block.
+	Count int
+	// Ref is the reference value of the FrameOfReference transform,
+	// which each block has one of.
+	Ref int64
+	// Groups describes the groups of the block, with offsets in the
+	// container.
+	Groups []GroupInfo
+}
+
+// InspectContainer reads the header of the container in data, written
+// by Marshal, and then calls fn for each of its blocks, stopping early
+// if fn returns false. The StreamInfo it returns describes the
+// container's header. Like Inspect, it is meant for tools and
+// debugging; blocks are checked against their checksums, but not
+// decoded.
+func InspectContainer(data []byte, fn func(BlockInfo) bool) (StreamInfo, error) {
+	p, count, i, err := parseContainer(data)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	info := StreamInfo{
+		Version:   p.version(),
+		Transform: p.transform(),
+		Size:      i,
+	}
+	for first := uint64(0); first < count; {
+		n, body, size, err := parseBlock(data[i:], count-first)
+		if err != nil {
+			return info, err
+		}
+		bi := BlockInfo{Offset: i, Count: int(n)}
+		off := i + size - 4 - len(body)
+		if p.transform() == FrameOfReference {
+			ref, k := binary.Varint(body)
+			if k <= 0 {
+				return info, ErrCorrupt
+			}
+			bi.Ref = ref
+			body = body[k:]
+			off += k
+		}
+		_, err = inspectGroups(body, off, p, func(g GroupInfo) bool {
+			bi.Groups = append(bi.Groups, g)
+			return true
+		})
+		if err != nil {
+			return info, err
+		}
+		if !fn(bi) {
+			return info, nil
+		}
+		i += size
+		first += n
+	}
+	return info, checkEnd(data, i)
+}
@@ -0,0 +1,86 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	vals := randRepeatSlice(20)
+	for version := 1; version <= MaxVersion; version++ {
+		opt := Options{Transform: FrameOfReference, Version: version}
+		buf := mustEncode(vals, opt)
+		var stored []int64
+		info, err := Inspect(buf, func(g GroupInfo) bool {
+			if !g.Repeat && g.Bits == 0 && buf[g.Offset] != nbytes(g.Count, g.Width) {
+				t.Fatalf("version %d: offset %d is not the group's header", version, g.Offset)
+			}
+			for j := 0; j < g.Count; j++ {
+				if g.Repeat {
+					stored = append(stored, g.Values[0])
+				} else {
+					stored = append(stored, g.Values[j])
+				}
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Version != version || info.Transform != FrameOfReference || info.Unsigned {
+			t.Fatalf("unexpected stream info %+v", info)
+		}
+		for i := range stored {
+			stored[i] += info.Ref
+		}
+		if !reflect.DeepEqual(vals, stored) {
+			t.Fatalf("version %d: groups did not hold the expected values", version)
+		}
+	}
+}
+
+func TestInspectContainer(t *testing.T) {
+	vals := randRepeatSlice(20)
+	opt := Options{Transform: FrameOfReference, Version: 2, BlockSize: 100, Index: true}
+	data := mustMarshal(vals, opt)
+	var stored []int64
+	blocks := 0
+	info, err := InspectContainer(data, func(b BlockInfo) bool {
+		if n, _ := binary.Uvarint(data[b.Offset:]); int(n) != b.Count {
+			t.Fatalf("offset %d is not the block's header", b.Offset)
+		}
+		start := len(stored)
+		for _, g := range b.Groups {
+			if !g.Repeat && g.Bits == 0 && data[g.Offset] != nbytes(g.Count, g.Width) {
+				t.Fatalf("offset %d is not the group's header", g.Offset)
+			}
+			for j := 0; j < g.Count; j++ {
+				if g.Repeat {
+					stored = append(stored, g.Values[0]+b.Ref)
+				} else {
+					stored = append(stored, g.Values[j]+b.Ref)
+				}
+			}
+		}
+		if len(stored)-start != b.Count {
+			t.Fatalf("block at %d: expected %d values, got %d", b.Offset, b.Count, len(stored)-start)
+		}
+		blocks++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != 2 || info.Transform != FrameOfReference {
+		t.Fatalf("unexpected container info %+v", info)
+	}
+	if blocks != (len(vals)+99)/100 || !reflect.DeepEqual(vals, stored) {
+		t.Fatalf("blocks did not hold the expected values")
+	}
+
+	data[len(data)/2] ^= 0x40
+	if _, err := InspectContainer(data, func(BlockInfo) bool { return true }); err == nil {
+		t.Fatalf("expected error inspecting damaged data")
+	}
+}
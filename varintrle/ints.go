@@ -0,0 +1,93 @@
+package varintrle
+
+import "errors"
+
+// Integer is any integer type. It matches
+// golang.org/x/exp/constraints.Integer, without depending on it.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// ErrOverflow is returned when decoding a value that does not fit in
+// the type decoded to.
+var ErrOverflow = errors.New("varintrle: value overflows type")
+
+func signed[T Integer]() bool {
+	var zero T
+	return ^zero < 0
+}
+
+// EncodeInts appends the encoding of vals to dst and returns the
+// extended buffer. Signed values are encoded the same as AppendRun
+// would, and unsigned values the same as AppendRunUint64 would, without
+// any zigzag encoding.
+func EncodeInts[T Integer](dst []byte, vals []T) []byte {
+	var rw runWriter
+	if signed[T]() {
+		for _, v := range vals {
+			dst = rw.add(dst, zigzag(int64(v)))
+		}
+		return rw.end(dst)
+	}
+	dst = appendPreamble(dst, preamble{flags: flagUnsigned})
+	for _, v := range vals {
+		dst = rw.add(dst, uint64(v))
+	}
+	return rw.end(dst)
+}
+
+// DecodeInts decodes all values in src and appends them to dst,
+// returning the extended slice. Signed types decode any stream that
+// DecodeRun does, and unsigned types any that DecodeRunUint64 does. A
+// value that does not fit in T stops decoding with ErrOverflow, and
+// like DecodeRun, streams of more values than MaxRepeat allows return
+// ErrLimit.
+func DecodeInts[T Integer](dst []T, src []byte) ([]T, error) {
+	isSigned := signed[T]()
+	p, i, err := parsePreamble(src)
+	if err == nil {
+		err = p.check(!isSigned)
+		if i == 0 && len(src) == 0 {
+			err = nil
+		}
+	}
+	if err == nil && !isSigned && p.transform() != NoTransform {
+		err = ErrNotPlain
+	}
+	if err == nil && p.version() > MaxVersion {
+		err = ErrVersion
+	}
+	if err != nil {
+		return dst, err
+	}
+	t := transformer{p: p}
+	s := newScanner(src[i:], p.version())
+	for left := decodeLimit(len(src)); ; {
+		g, ok := s.next()
+		if !ok {
+			return dst, s.err
+		}
+		if g.n > left {
+			return dst, ErrLimit
+		}
+		left -= g.n
+		for j := 0; j < g.n; j++ {
+			u := g.value(j)
+			var v T
+			if isSigned {
+				x := t.undo(unzigzag(u))
+				v = T(x)
+				if int64(v) != x {
+					return dst, ErrOverflow
+				}
+			} else {
+				v = T(u)
+				if uint64(v) != u {
+					return dst, ErrOverflow
+				}
+			}
+			dst = append(dst, v)
+		}
+	}
+}
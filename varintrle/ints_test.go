@@ -0,0 +1,60 @@
+package varintrle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestInts(t *testing.T) {
+	i32 := []int32{0, -1, 1, -1 << 31, 1<<31 - 1, 5, 5, 5}
+	buf := EncodeInts(nil, i32)
+	if !bytes.Equal(buf, AppendRun(nil, []int64{0, -1, 1, -1 << 31, 1<<31 - 1, 5, 5, 5})) {
+		t.Fatalf("did not match AppendRun output")
+	}
+	actual32, err := DecodeInts[int32](nil, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(i32, actual32) {
+		t.Fatalf("did not match expected output")
+	}
+
+	u16 := []uint16{0, 0x80, 0xffff, 7}
+	buf = EncodeInts(nil, u16)
+	if !bytes.Equal(buf, AppendRunUint64(nil, []uint64{0, 0x80, 0xffff, 7})) {
+		t.Fatalf("did not match AppendRunUint64 output")
+	}
+	actual16, err := DecodeInts[uint16](nil, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u16, actual16) {
+		t.Fatalf("did not match expected output")
+	}
+	if _, err := DecodeInts[int16](nil, buf); err != ErrSignedness {
+		t.Fatalf("expected ErrSignedness, got %v", err)
+	}
+	if _, err := DecodeInts[uint8](nil, buf); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+
+	// any stream DecodeRun reads
+	vals := randRepeatSlice(20)
+	for _, opt := range []Options{{Version: 2, Transform: Delta}, {Version: 3}} {
+		actual, err := DecodeInts[int](nil, mustEncode(vals, opt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range vals {
+			if int64(actual[i]) != vals[i] {
+				t.Fatalf("%+v: did not match expected output", opt)
+			}
+		}
+	}
+	hostile := binary.AppendUvarint([]byte{preambleHeader, 0, 1 << flagVersionShift, repeatHeader(0)}, 1<<34)
+	if actual, err := DecodeInts[int8](nil, hostile); err != ErrLimit || cap(actual) != 0 {
+		t.Fatalf("expected ErrLimit for a long repeat run, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package varintrle
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelChunk is the number of values EncodeParallel groups at a
+// time. No group spans two chunks, so the output is the same no matter
+// how the chunks are shared among workers.
+const parallelChunk = 1 << 16
+
+// EncodeParallel encodes vals like AppendRun, but on up to workers
+// goroutines at once, each encoding chunks of vals independently. The
+// chunks are joined into one version 1 stream, which any decoder of
+// the package reads; it differs from AppendRun's only in that groups
+// end at every chunk boundary. The output is the same no matter the
+// number of workers. If workers is 0 or less, runtime.GOMAXPROCS(0)
+// are used.
+func EncodeParallel(vals []int64, workers int) []byte {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	nchunks := (len(vals) + parallelChunk - 1) / parallelChunk
+	if workers == 1 || nchunks <= 1 {
+		var dst []byte
+		for pos := 0; pos < len(vals); pos += parallelChunk {
+			dst = AppendRun(dst, vals[pos:min(pos+parallelChunk, len(vals))])
+		}
+		return dst
+	}
+	chunks := make([][]byte, nchunks)
+	parallel(nchunks, workers, func(c int) {
+		pos := c * parallelChunk
+		chunks[c] = AppendRun(nil, vals[pos:min(pos+parallelChunk, len(vals))])
+	})
+	size := 0
+	for _, chunk := range chunks {
+		size += len(chunk)
+	}
+	dst := make([]byte, 0, size)
+	for _, chunk := range chunks {
+		dst = append(dst, chunk...)
+	}
+	return dst
+}
+
+// DecodeParallel decodes all values in src like DecodeRun, returning
+// the same values and error, but decodes version 1 streams without a
+// transform, such as EncodeParallel writes, on up to workers goroutines
+// at once. It splits them between groups, which it finds by reading
+// only the headers. Other streams are decoded by DecodeRun. If workers
+// is 0 or less, runtime.GOMAXPROCS(0) are used.
+func DecodeParallel(src []byte, workers int) ([]int64, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p, i, err := parsePreamble(src)
+	if err == nil && i > 0 {
+		err = p.check(false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if workers == 1 || p.version() != 1 || p.transform() != NoTransform {
+		return DecodeRun(nil, src)
+	}
+	src = src[i:]
+
+	// split the groups into segments of about the same size, a few for
+	// each worker, so that none is left with much more than the others
+	type segment struct {
+		start, end int // in src
+		first      int // of the values
+	}
+	size := max(len(src)/(workers*4), 1<<12)
+	var segs []segment
+	seg := segment{}
+	total := 0
+	for j := 0; j < len(src); {
+		n, bytes := getnbytes(src[j])
+		j += 1 + n*bytes
+		total += n
+		if j-seg.start >= size || j >= len(src) {
+			seg.end = min(j, len(src))
+			segs = append(segs, seg)
+			seg = segment{start: seg.end, first: total}
+		}
+	}
+	segs = append(segs, seg)
+
+	// at most 32 values for each byte, so total needs no limit of its
+	// own
+	var vals []int64
+	if total > 0 {
+		vals = make([]int64, total)
+	}
+	errs := make([]error, len(segs)-1)
+	ends := make([]int, len(segs)-1)
+	parallel(len(segs)-1, workers, func(s int) {
+		first, last := segs[s].first, segs[s+1].first
+		out, err := decodeRun(vals[first:first:last], src[segs[s].start:segs[s].end], last-first)
+		ends[s], errs[s] = first+len(out), err
+	})
+	for s, err := range errs {
+		if err != nil {
+			return vals[:ends[s]], err
+		}
+	}
+	return vals, nil
+}
+
+// parallel calls f for 0 up to n, on up to workers goroutines at once.
+func parallel(n, workers int, f func(i int)) {
+	workers = min(workers, n)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
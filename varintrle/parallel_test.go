@@ -0,0 +1,89 @@
+package varintrle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	vals := randIntSlice(10000)
+	if len(vals) < 2*parallelChunk {
+		t.Fatalf("expected more than two chunks of values, got %d", len(vals))
+	}
+	expected := EncodeParallel(vals, 1)
+	for _, workers := range []int{0, 1, 2, 7} {
+		data := EncodeParallel(vals, workers)
+		if !bytes.Equal(data, expected) {
+			t.Fatalf("%d workers: did not match output of 1 worker", workers)
+		}
+		actual, err := DecodeParallel(data, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("%d workers: did not match expected output", workers)
+		}
+	}
+
+	// a plain stream, which sequential decoders read too
+	actual, err := DecodeRun(nil, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("DecodeRun did not match expected output")
+	}
+	if plain := AppendRun(nil, vals); len(expected) > len(plain)+len(vals)/parallelChunk {
+		t.Fatalf("expected about %d bytes, got %d", len(plain), len(expected))
+	}
+
+	// cut short, it decodes to what DecodeRun does
+	short := expected[:len(expected)-3]
+	loose, err := DecodeRun(nil, short)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	actual, err = DecodeParallel(short, 4)
+	if err != io.ErrUnexpectedEOF || !reflect.DeepEqual(loose, actual) {
+		t.Fatalf("truncated stream did not match DecodeRun: %v", err)
+	}
+
+	// other streams are decoded by DecodeRun
+	data := mustEncode(vals, Options{Transform: Delta, Version: 2})
+	actual, err = DecodeParallel(data, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+	data = binary.AppendUvarint([]byte{preambleHeader, 0, 1 << flagVersionShift, repeatHeader(0)}, 1<<34)
+	if _, err := DecodeParallel(data, 4); err != ErrLimit {
+		t.Fatalf("expected ErrLimit for a long repeat run, got %v", err)
+	}
+}
+
+func BenchmarkEncodeParallel(b *testing.B) {
+	vals := randIntSlice(100000)
+	b.SetBytes(int64(len(vals) * 8))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EncodeParallel(vals, 0)
+	}
+}
+
+func BenchmarkDecodeParallel(b *testing.B) {
+	vals := randIntSlice(100000)
+	data := EncodeParallel(vals, 0)
+	b.SetBytes(int64(len(vals) * 8))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := DecodeParallel(data, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
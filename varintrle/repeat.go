@@ -0,0 +1,276 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// Version 2 streams add repeat runs, which store a value once along
+// with how many times it repeats, so that long runs of any value take
+// O(1) space. To make room for them in the header, groups of zeros hold
+// at most 16 values, leaving the headers of longer zero groups free:
+//
+//	nnnnnwww  w != 0:   n+1 values of width w, as in version 1
+//	0nnnn000            n+1 zeros
+//	1eeee000  e < 8:    a repeat run of a value of width e, followed by
+//	                    the run length as a uvarint, and then the value
+//
+// The rest are bit-packed groups, described in bits.go.
+const (
+	maxZeros2    = 16
+	headerRepeat = 0x80
+)
+
+const maxInt = int(^uint(0) >> 1)
+
+// MaxRepeat bounds how far repeat runs can grow the output of the
+// decoders which return a slice, such as DecodeRun, so that a few bytes
+// of damaged input cannot make them allocate without limit. They decode
+// at most 32 values for each byte of input, which is as many as groups
+// other than repeat runs can hold, plus MaxRepeat, and return ErrLimit
+// past that. DecodeStrict takes a limit of its own, and a Decoder,
+// which allocates nothing, has none.
+const MaxRepeat = 1 << 24
+
+// decodeLimit returns the most values the decoders which return a
+// slice decode from size bytes of input.
+func decodeLimit(size int) int {
+	if size > (maxInt-MaxRepeat)/32 {
+		return maxInt
+	}
+	return size*32 + MaxRepeat
+}
+
+// A version 2 header is either a group like in version 1, or an
+// extended header.
+func getheader2(b uint8) (n, bytes int, extended bool) {
+	if b&0x87 == 0x80 {
+		return 0, 0, true
+	}
+	n, bytes = getnbytes(b)
+	return n, bytes, false
+}
+
+// repeat returns the width of the value of a repeat run header.
+func getrepeat(b uint8) (bytes int, ok bool) {
+	e := (b >> 3) & 0xf
+	if e >= 8 {
+		return 0, false
+	}
+	return getnbytesLUT[e], true
+}
+
+func repeatHeader(bytes int) uint8 {
+	if bytes == 8 {
+		bytes = 7
+	}
+	return headerRepeat | uint8(bytes)<<3
+}
+
+// packer groups values as they are appended to a buffer, for any
+// version of the format.
+type packer struct {
+	rw      runWriter
+	split   splitWriter
+	version int
+
+	// the pending run of val, in version 2
+	val   uint64
+	count int
+
+	// values waiting to be grouped, in version 2, which are bit-packed
+	// if that is smaller than grouping them by bytes
+	window  [32]uint64
+	nwindow int
+}
+
+func newPacker(version int) packer {
+	p := packer{version: version}
+	if version == 2 {
+		p.rw.maxZeros = maxZeros2
+	}
+	return p
+}
+
+func (p *packer) add(dst []byte, v uint64) []byte {
+	switch p.version {
+	case 2:
+	case 3:
+		return p.split.add(dst, v)
+	default:
+		return p.rw.add(dst, v)
+	}
+	if p.count > 0 && v == p.val {
+		p.count++
+		return dst
+	}
+	dst = p.endRun(dst)
+	p.val = v
+	p.count = 1
+	return dst
+}
+
+// addRun adds count copies of v, in version 2.
+func (p *packer) addRun(dst []byte, v uint64, count int) []byte {
+	if p.count > 0 && v == p.val {
+		p.count += count
+		return dst
+	}
+	dst = p.endRun(dst)
+	p.val = v
+	p.count = count
+	return dst
+}
+
+// endRun writes out the pending run, as a repeat run if that is
+// smaller, or else as plain values.
+func (p *packer) endRun(dst []byte) []byte {
+	if p.count == 0 {
+		return dst
+	}
+	size := widthOf(p.val)
+	if size == 7 {
+		size = 8
+	}
+	perGroup := 32
+	if size == 0 {
+		perGroup = maxZeros2
+	}
+	plain := p.count*size + (p.count+perGroup-1)/perGroup
+	// or less, if they can be bit-packed with their neighbours
+	plain = min(plain, bitsSize(p.count, max(bits.Len64(p.val), 1)))
+	// a repeat run also splits the group it interrupts
+	repeat := 2 + uvarintLen(uint64(p.count)) + size
+	if repeat < plain {
+		dst = p.rw.end(p.flushWindow(dst))
+		dst = append(dst, repeatHeader(size))
+		dst = binary.AppendUvarint(dst, uint64(p.count))
+		dst = appendPayload(dst, p.val, size)
+	} else {
+		for ; p.count > 0; p.count-- {
+			dst = p.push(dst, p.val)
+		}
+	}
+	p.count = 0
+	return dst
+}
+
+func (p *packer) end(dst []byte) []byte {
+	if p.version == 3 {
+		return p.split.end(dst)
+	}
+	return p.rw.end(p.flushWindow(p.endRun(dst)))
+}
+
+func (p *packer) push(dst []byte, v uint64) []byte {
+	p.window[p.nwindow] = v
+	p.nwindow++
+	if p.nwindow == len(p.window) {
+		return p.flushWindow(dst)
+	}
+	return dst
+}
+
+// flushWindow writes out the values waiting to be grouped, as a single
+// bit-packed group if that is smaller, or else grouped by bytes.
+func (p *packer) flushWindow(dst []byte) []byte {
+	vals := p.window[:p.nwindow]
+	p.nwindow = 0
+	nbits := 0
+	for _, v := range vals {
+		nbits = max(nbits, bits.Len64(v))
+	}
+	if nbits > 0 && 2+bitsSize(len(vals), nbits) < p.rw.cost(vals) {
+		dst = p.rw.end(dst)
+		return appendBitGroup(dst, vals, nbits)
+	}
+	for _, v := range vals {
+		dst = p.rw.add(dst, v)
+	}
+	return dst
+}
+
+func uvarintLen(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
+
+// decodeRun2 is decodeRun for version 2 streams.
+func decodeRun2(dst []int64, src []byte, max int) ([]int64, error) {
+	i := 0
+	for left := max; i < len(src); {
+		n, bytes, extended := getheader2(src[i])
+		i++
+		if extended && isBitGroup(src[i-1]) {
+			if i == len(src) {
+				return dst, io.ErrUnexpectedEOF
+			}
+			n, nbits := getbits(src[i-1], src[i])
+			if n > left {
+				return dst, ErrLimit
+			}
+			left -= n
+			i++
+			size := bitsSize(n, nbits)
+			if len(src)-i < size {
+				return dst, io.ErrUnexpectedEOF
+			}
+			for j := 0; j < n; j++ {
+				dst = append(dst, unzigzag(readBits(src[i:], j, nbits)))
+			}
+			i += size
+			continue
+		}
+		if extended {
+			bytes, ok := getrepeat(src[i-1])
+			if !ok {
+				return dst, ErrCorrupt
+			}
+			count, k := binary.Uvarint(src[i:])
+			if k == 0 {
+				return dst, io.ErrUnexpectedEOF
+			}
+			if k < 0 || count == 0 || count > uint64(maxInt) {
+				return dst, ErrCorrupt
+			}
+			if count > uint64(left) {
+				return dst, ErrLimit
+			}
+			left -= int(count)
+			i += k
+			if len(src)-i < bytes {
+				return dst, io.ErrUnexpectedEOF
+			}
+			v := unzigzag(readPayload(src, i, bytes, payloadMask[bytes]))
+			i += bytes
+			for ; count > 0; count-- {
+				dst = append(dst, v)
+			}
+			continue
+		}
+		if n > left {
+			return dst, ErrLimit
+		}
+		left -= n
+		if bytes == 0 {
+			for ; n > 0; n-- {
+				dst = append(dst, 0)
+			}
+			continue
+		}
+		if len(src)-i < n*bytes {
+			return dst, io.ErrUnexpectedEOF
+		}
+		mask := payloadMask[bytes]
+		for ; n > 0; n-- {
+			dst = append(dst, unzigzag(readPayload(src, i, bytes, mask)))
+			i += bytes
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,124 @@
+package varintrle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randRepeatSlice(perms int) []int64 {
+	var vals []int64
+	for i := 0; i < perms; i++ {
+		switch rand.Intn(3) {
+		case 0:
+			vals = append(vals, randIntSlice(1)...)
+		default:
+			v := randIntSlice(1)[0]
+			for n := rand.Intn(200) + 1; n > 0; n-- {
+				vals = append(vals, v)
+			}
+		}
+	}
+	return vals
+}
+
+func TestRepeatRuns(t *testing.T) {
+	opt := Options{Version: 2}
+	table := []struct {
+		vals []int64
+		max  int
+	}{
+		{make([]int64, 100000), 8},
+		{append(make([]int64, 1000), 7, 7, 7), 12},
+	}
+	for i := range table[1].vals[:1000] {
+		table[1].vals[i] = 7
+	}
+	for _, tt := range table {
+		buf := mustEncode(tt.vals, opt)
+		if len(buf) > tt.max {
+			t.Fatalf("expected at most %d bytes, got %d", tt.max, len(buf))
+		}
+		actual, err := DecodeRun(nil, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.vals, actual) {
+			t.Fatalf("did not match expected output")
+		}
+	}
+}
+
+func TestRepeatRunLimit(t *testing.T) {
+	// a version 2 preamble and a run of 1<<34 zeros, in 8 bytes
+	src := binary.AppendUvarint([]byte{preambleHeader, 0, 1 << flagVersionShift, repeatHeader(0)}, 1<<34)
+	vals, err := DecodeRun(nil, src)
+	if err != ErrLimit || cap(vals) > 0 {
+		t.Fatalf("expected ErrLimit before expanding the run, got %v", err)
+	}
+
+	// while runs within the limit still decode
+	long := make([]int64, MaxRepeat)
+	vals, err = DecodeRun(nil, mustEncode(long, Options{Version: 2}))
+	if err != nil || len(vals) != len(long) {
+		t.Fatalf("expected %d values, got %d: %v", len(long), len(vals), err)
+	}
+}
+
+func TestRepeatRunsRandom(t *testing.T) {
+	vals := randRepeatSlice(50)
+	for _, tr := range []Transform{NoTransform, Delta, DeltaOfDelta} {
+		opt := Options{Transform: tr, Version: 2}
+		buf := mustEncode(vals, opt)
+		actual, err := DecodeRun(nil, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("transform %d: did not match expected output", tr)
+		}
+
+		w := &bytes.Buffer{}
+		enc := NewEncoderOptions(w, opt)
+		err = enc.WriteSlice(vals)
+		if err == nil {
+			err = enc.Close()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// feed the Decoder a byte at a time, to pause within headers
+		src := &bytes.Buffer{}
+		dec := NewDecoder(src)
+		actual = actual[:0]
+		for _, b := range w.Bytes() {
+			src.WriteByte(b)
+			for {
+				v, err := dec.Next()
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual = append(actual, v)
+			}
+		}
+		if !reflect.DeepEqual(vals, actual) {
+			t.Fatalf("transform %d: Decoder did not match expected output", tr)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	buf := appendPreamble(nil, preamble{flags: MaxVersion << flagVersionShift})
+	if _, err := DecodeRun(nil, buf); err != ErrVersion {
+		t.Fatalf("expected ErrVersion, got %v", err)
+	}
+	if _, err := NewDecoder(bytes.NewReader(buf)).Next(); err != ErrVersion {
+		t.Fatalf("expected ErrVersion, got %v", err)
+	}
+}
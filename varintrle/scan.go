@@ -0,0 +1,204 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// group is a group of values as found by a scanner.
+type group struct {
+	// n values of the given width in bytes
+	n     int
+	bytes int
+	// if set, the group is a repeat run of the value in data
+	repeat bool
+	// if set, the values are packed to this many bits each
+	bits int
+	// the payloads of the values
+	data []byte
+}
+
+// value returns the i'th value of the group, as it was stored.
+func (g *group) value(i int) uint64 {
+	if g.bits > 0 {
+		return readBits(g.data, i, g.bits)
+	}
+	if g.bytes == 0 {
+		return 0
+	}
+	if g.repeat {
+		i = 0
+	}
+	return readPayload(g.data, i*g.bytes, g.bytes, payloadMask[g.bytes])
+}
+
+// uniform reports whether all values of the group are the same.
+func (g *group) uniform() bool {
+	return g.repeat || g.bits == 0 && g.bytes == 0
+}
+
+// scanner walks through the groups of a stream without decoding them,
+// for code that can make use of the groups themselves, or does not
+// know the type of its output up front.
+type scanner struct {
+	src     []byte
+	off     int
+	version int
+	err     error
+
+	// the offset of the header of the last group returned
+	hdr int
+
+	// the rest of the current chunk, in version 3, and the offset of
+	// its next header
+	ctrl    []byte
+	data    []byte
+	ctrlOff int
+}
+
+func newScanner(src []byte, version int) scanner {
+	return scanner{
+		src:     src,
+		version: version,
+	}
+}
+
+// next returns the next group, or false at the end of the stream or on
+// an error, which is then left in err.
+func (s *scanner) next() (g group, ok bool) {
+	if s.err != nil {
+		return g, false
+	}
+	if s.version == 3 {
+		return s.next3()
+	}
+	if s.off >= len(s.src) {
+		return g, false
+	}
+	s.hdr = s.off
+	b := s.src[s.off]
+	if s.version == 2 {
+		n, bytes, extended := getheader2(b)
+		if extended && isBitGroup(b) {
+			return s.bitGroup(b)
+		}
+		if extended {
+			return s.repeat(b)
+		}
+		g.n, g.bytes = n, bytes
+	} else {
+		g.n, g.bytes = getnbytes(b)
+	}
+	s.off++
+	size := g.n * g.bytes
+	if len(s.src)-s.off < size {
+		s.err = io.ErrUnexpectedEOF
+		return g, false
+	}
+	g.data = s.src[s.off : s.off+size]
+	s.off += size
+	return g, true
+}
+
+func (s *scanner) repeat(b uint8) (g group, ok bool) {
+	bytes, ok := getrepeat(b)
+	if !ok {
+		s.err = ErrCorrupt
+		return g, false
+	}
+	count, k := binary.Uvarint(s.src[s.off+1:])
+	if k == 0 {
+		s.err = io.ErrUnexpectedEOF
+		return g, false
+	}
+	if k < 0 || count == 0 || count > uint64(maxInt) {
+		s.err = ErrCorrupt
+		return g, false
+	}
+	i := s.off + 1 + k
+	if len(s.src)-i < bytes {
+		s.err = io.ErrUnexpectedEOF
+		return g, false
+	}
+	g = group{
+		n:      int(count),
+		bytes:  bytes,
+		repeat: true,
+		data:   s.src[i : i+bytes],
+	}
+	s.off = i + bytes
+	return g, true
+}
+
+func (s *scanner) bitGroup(b uint8) (g group, ok bool) {
+	i := s.off + 1
+	if i == len(s.src) {
+		s.err = io.ErrUnexpectedEOF
+		return g, false
+	}
+	g.n, g.bits = getbits(b, s.src[i])
+	i++
+	size := bitsSize(g.n, g.bits)
+	if len(s.src)-i < size {
+		s.err = io.ErrUnexpectedEOF
+		return g, false
+	}
+	g.data = s.src[i : i+size]
+	s.off = i + size
+	return g, true
+}
+
+func (s *scanner) next3() (g group, ok bool) {
+	if len(s.ctrl) == 0 {
+		if s.off >= len(s.src) {
+			return g, false
+		}
+		s.hdr = s.off
+		ctrl, data, size, err := parseChunk(s.src[s.off:])
+		if err != nil {
+			s.err = err
+			return g, false
+		}
+		s.ctrl, s.data = ctrl, data
+		s.ctrlOff = s.off + size - len(ctrl) - len(data)
+		s.off += size
+	}
+	s.hdr = s.ctrlOff
+	s.ctrlOff++
+	g.n, g.bytes = getnbytes(s.ctrl[0])
+	s.ctrl = s.ctrl[1:]
+	size := g.n * g.bytes
+	if len(s.data) < size || len(s.ctrl) == 0 && len(s.data) != size {
+		s.err = ErrCorrupt
+		return g, false
+	}
+	g.data = s.data[:size]
+	s.data = s.data[size:]
+	return g, true
+}
+
+// values walks through the values of a stream one at a time, as they
+// were stored.
+type values struct {
+	s scanner
+	g group
+	i int
+}
+
+func newValues(src []byte, version int) values {
+	return values{
+		s: newScanner(src, version),
+	}
+}
+
+func (v *values) next() (uint64, bool) {
+	for v.i >= v.g.n {
+		g, ok := v.s.next()
+		if !ok {
+			return 0, false
+		}
+		v.g, v.i = g, 0
+	}
+	v.i++
+	return v.g.value(v.i - 1), true
+}
@@ -0,0 +1,257 @@
+package varintrle
+
+import "iter"
+
+// A Sparse is a vector of integers kept in encoded form, in a version 2
+// stream. Runs of zeros, and of any other value, take O(1) space, and
+// the methods of Sparse skip over them whole by walking the headers of
+// the stream, without expanding it.
+type Sparse struct {
+	buf     []byte
+	off     int
+	version int
+	n       int
+}
+
+// NewSparse returns a Sparse holding vals.
+func NewSparse(vals []int64) *Sparse {
+	opt := Options{Version: 2}
+	p := makePreamble(opt)
+	return &Sparse{
+		buf:     appendRunOptions(nil, vals, opt),
+		off:     preambleSize,
+		version: p.version(),
+		n:       len(vals),
+	}
+}
+
+// ParseSparse returns a Sparse holding the values of the stream in src,
+// which must be written without a transform. The Sparse refers to src
+// rather than copying it.
+func ParseSparse(src []byte) (*Sparse, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil {
+		err = checkCopyable(p)
+	}
+	if err == nil && p.flags&flagUnsigned != 0 {
+		err = ErrSignedness
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Sparse{
+		buf:     src,
+		off:     i,
+		version: p.version(),
+	}
+	sc := s.scanner()
+	for {
+		g, ok := sc.next()
+		if !ok {
+			return s, sc.err
+		}
+		s.n += g.n
+	}
+}
+
+// Bytes returns the stream holding the values of s.
+func (s *Sparse) Bytes() []byte {
+	return s.buf
+}
+
+// Len returns the length of s.
+func (s *Sparse) Len() int {
+	return s.n
+}
+
+func (s *Sparse) scanner() scanner {
+	return newScanner(s.buf[s.off:], s.version)
+}
+
+// Get returns the value at position i of s. It panics if i is out of
+// range.
+func (s *Sparse) Get(i int) int64 {
+	if i < 0 || i >= s.n {
+		panic("varintrle: index out of range")
+	}
+	sc := s.scanner()
+	for {
+		g, _ := sc.next()
+		if i < g.n {
+			return unzigzag(g.value(i))
+		}
+		i -= g.n
+	}
+}
+
+// All returns an iterator over the positions and values of the non-zero
+// values of s.
+func (s *Sparse) All() iter.Seq2[int, int64] {
+	return func(yield func(int, int64) bool) {
+		sc := s.scanner()
+		pos := 0
+		for {
+			g, ok := sc.next()
+			if !ok {
+				return
+			}
+			if !g.uniform() || g.value(0) != 0 {
+				for j := 0; j < g.n; j++ {
+					v := unzigzag(g.value(j))
+					if v != 0 && !yield(pos+j, v) {
+						return
+					}
+				}
+			}
+			pos += g.n
+		}
+	}
+}
+
+// Dot returns the dot product of s and t. It panics if they differ in
+// length.
+func (s *Sparse) Dot(t *Sparse) int64 {
+	if s.n != t.n {
+		panic("varintrle: vectors differ in length")
+	}
+	var sum int64
+	a, b := s.cursor(), t.cursor()
+	for {
+		k := min(a.left(), b.left())
+		if k == 0 {
+			return sum
+		}
+		x, xok := a.constant()
+		y, yok := b.constant()
+		switch {
+		case xok && x == 0 || yok && y == 0:
+		case xok && yok:
+			sum += x * y * int64(k)
+		case xok:
+			sum += x * b.sum(k)
+		case yok:
+			sum += y * a.sum(k)
+		default:
+			for j := 0; j < k; j++ {
+				sum += a.value(j) * b.value(j)
+			}
+		}
+		a.i += k
+		b.i += k
+	}
+}
+
+// DotDense returns the dot product of s and vals. It panics if they
+// differ in length.
+func (s *Sparse) DotDense(vals []int64) int64 {
+	if s.n != len(vals) {
+		panic("varintrle: vectors differ in length")
+	}
+	var sum int64
+	a := s.cursor()
+	for pos := 0; ; {
+		k := a.left()
+		if k == 0 {
+			return sum
+		}
+		if x, ok := a.constant(); ok {
+			if x != 0 {
+				var t int64
+				for _, v := range vals[pos : pos+k] {
+					t += v
+				}
+				sum += x * t
+			}
+		} else {
+			for j := 0; j < k; j++ {
+				sum += a.value(j) * vals[pos+j]
+			}
+		}
+		a.i += k
+		pos += k
+	}
+}
+
+// Add returns a new Sparse holding the sum of s and t. It panics if
+// they differ in length.
+func (s *Sparse) Add(t *Sparse) *Sparse {
+	if s.n != t.n {
+		panic("varintrle: vectors differ in length")
+	}
+	opt := Options{Version: 2}
+	p := makePreamble(opt)
+	dst := appendPreamble(nil, p)
+	pk := newPacker(p.version())
+	a, b := s.cursor(), t.cursor()
+	for {
+		k := min(a.left(), b.left())
+		if k == 0 {
+			break
+		}
+		x, xok := a.constant()
+		y, yok := b.constant()
+		if xok && yok {
+			dst = pk.addRun(dst, zigzag(x+y), k)
+		} else {
+			for j := 0; j < k; j++ {
+				dst = pk.add(dst, zigzag(a.value(j)+b.value(j)))
+			}
+		}
+		a.i += k
+		b.i += k
+	}
+	return &Sparse{
+		buf:     pk.end(dst),
+		off:     preambleSize,
+		version: p.version(),
+		n:       s.n,
+	}
+}
+
+func (s *Sparse) cursor() cursor {
+	return cursor{s: s.scanner()}
+}
+
+// cursor walks through the groups of a stream a number of values at a
+// time.
+type cursor struct {
+	s scanner
+	g group
+	i int
+}
+
+// left returns the number of values left in the current group, moving
+// on to the next group if there are none, or 0 at the end.
+func (c *cursor) left() int {
+	for c.i >= c.g.n {
+		g, ok := c.s.next()
+		if !ok {
+			return 0
+		}
+		c.g, c.i = g, 0
+	}
+	return c.g.n - c.i
+}
+
+// constant returns the value of the current group, if all of its values
+// are the same.
+func (c *cursor) constant() (int64, bool) {
+	if c.g.uniform() {
+		return unzigzag(c.g.value(0)), true
+	}
+	return 0, false
+}
+
+// value returns the j'th value left in the current group.
+func (c *cursor) value(j int) int64 {
+	return unzigzag(c.g.value(c.i + j))
+}
+
+// sum returns the sum of the next k values in the current group.
+func (c *cursor) sum(k int) int64 {
+	var t int64
+	for j := 0; j < k; j++ {
+		t += c.value(j)
+	}
+	return t
+}
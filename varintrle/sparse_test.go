@@ -0,0 +1,79 @@
+package varintrle
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randSparse(n int) []int64 {
+	vals := make([]int64, n)
+	for i := 0; i < n/500; i++ {
+		j := rand.Intn(n)
+		for k := rand.Intn(40); k > 0 && j < n; k-- {
+			vals[j] = int64(rand.Intn(2000) - 1000)
+			j++
+		}
+	}
+	return vals
+}
+
+func TestSparse(t *testing.T) {
+	const n = 10000
+	a, b := randSparse(n), randSparse(n)
+	// runs of the same value other than zero
+	for i := 100; i < 300; i++ {
+		a[i] = 7
+	}
+	sa, sb := NewSparse(a), NewSparse(b)
+	if sa.Len() != n || len(sa.Bytes()) > n/4 {
+		t.Fatalf("unexpected length %d or size %d", sa.Len(), len(sa.Bytes()))
+	}
+	for i, v := range a {
+		if sa.Get(i) != v {
+			t.Fatalf("Get(%d) = %d, expected %d", i, sa.Get(i), v)
+		}
+	}
+	next := 0
+	for i, v := range sa.All() {
+		for ; next < i; next++ {
+			if a[next] != 0 {
+				t.Fatalf("All skipped non-zero value at %d", next)
+			}
+		}
+		if v == 0 || a[i] != v {
+			t.Fatalf("All yielded %d at %d, expected %d", v, i, a[i])
+		}
+		next = i + 1
+	}
+
+	var dot int64
+	sum := make([]int64, n)
+	for i := range a {
+		dot += a[i] * b[i]
+		sum[i] = a[i] + b[i]
+	}
+	if d := sa.Dot(sb); d != dot {
+		t.Fatalf("Dot = %d, expected %d", d, dot)
+	}
+	if d := sa.DotDense(b); d != dot {
+		t.Fatalf("DotDense = %d, expected %d", d, dot)
+	}
+	added := sa.Add(sb)
+	actual, err := DecodeRun(nil, added.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sum {
+		if actual[i] != sum[i] {
+			t.Fatalf("Add did not match expected output at %d", i)
+		}
+	}
+
+	parsed, err := ParseSparse(AppendRun(nil, a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Len() != n || parsed.Dot(sb) != dot {
+		t.Fatalf("parsed Sparse did not match")
+	}
+}
@@ -0,0 +1,218 @@
+package varintrle
+
+import (
+	"encoding/binary"
+	"io"
+	"slices"
+)
+
+// Version 3 streams hold the same groups as version 1, but split into
+// chunks which keep the headers apart from the payloads, much like
+// stream-vbyte:
+//
+//	n        uvarint number of headers
+//	size     uvarint size of the payloads
+//	headers  n headers, as in version 1
+//	payload  the payloads of all n groups
+//
+// Since the headers of a chunk come first, a decoder knows how many
+// values the chunk holds, and where each group's payload is, before
+// unpacking any of them. decodeChunk sizes its output once per chunk,
+// and unpacks each group whole with an unpacker looked up by its
+// header, with no branches on the values. The unpackers are plain Go,
+// so there is no separate fallback; they decode the same values as
+// decodeRun, value by value, does from the same groups.
+const maxChunkGroups = 256
+
+// splitWriter groups values like runWriter, but writes them out in
+// chunks.
+type splitWriter struct {
+	ctrl  []byte
+	data  []byte
+	n     int
+	width int
+}
+
+func (s *splitWriter) add(dst []byte, v uint64) []byte {
+	width := widthOf(v)
+	if s.n > 0 && (width != s.width || s.n == 32) {
+		s.endGroup()
+		if len(s.ctrl) == maxChunkGroups {
+			dst = s.flush(dst)
+		}
+	}
+	s.data = appendPayload(s.data, v, width)
+	s.n++
+	s.width = width
+	return dst
+}
+
+func (s *splitWriter) endGroup() {
+	s.ctrl = append(s.ctrl, nbytes(s.n, s.width))
+	s.n = 0
+}
+
+func (s *splitWriter) end(dst []byte) []byte {
+	if s.n > 0 {
+		s.endGroup()
+	}
+	return s.flush(dst)
+}
+
+func (s *splitWriter) flush(dst []byte) []byte {
+	if len(s.ctrl) == 0 {
+		return dst
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(s.ctrl)))
+	dst = binary.AppendUvarint(dst, uint64(len(s.data)))
+	dst = append(dst, s.ctrl...)
+	dst = append(dst, s.data...)
+	s.ctrl = s.ctrl[:0]
+	s.data = s.data[:0]
+	return dst
+}
+
+// parseChunk returns the headers and payload of the chunk at the start
+// of src, and the size of the chunk. If src holds only part of the
+// chunk, but all of its size fields, the size is returned along with
+// io.ErrUnexpectedEOF.
+func parseChunk(src []byte) (ctrl, data []byte, size int, err error) {
+	n, i := binary.Uvarint(src)
+	if i == 0 {
+		return nil, nil, 0, io.ErrUnexpectedEOF
+	}
+	if i < 0 || n == 0 || n > maxChunkGroups {
+		return nil, nil, 0, ErrCorrupt
+	}
+	dsize, k := binary.Uvarint(src[i:])
+	if k == 0 {
+		return nil, nil, 0, io.ErrUnexpectedEOF
+	}
+	if k < 0 || dsize > maxChunkGroups*32*8 {
+		return nil, nil, 0, ErrCorrupt
+	}
+	i += k
+	if uint64(len(src)-i) < n+dsize {
+		return nil, nil, i + int(n+dsize), io.ErrUnexpectedEOF
+	}
+	ctrl = src[i : i+int(n)]
+	i += int(n)
+	data = src[i : i+int(dsize)]
+	return ctrl, data, i + int(dsize), nil
+}
+
+// decodeRun3 is decodeRun for version 3 streams.
+func decodeRun3(dst []int64, src []byte, max int) ([]int64, error) {
+	for start := len(dst); len(src) > 0; {
+		ctrl, data, size, err := parseChunk(src)
+		if err != nil {
+			return dst, err
+		}
+		dst, err = decodeChunk(dst, ctrl, data, max-(len(dst)-start))
+		if err != nil {
+			return dst, err
+		}
+		src = src[size:]
+	}
+	return dst, nil
+}
+
+// A groupShape is what a header says about its group: the number of
+// values, the size of their payload, and how to unpack them.
+type groupShape struct {
+	n      uint8
+	size   uint16
+	unpack func(dst []int64, src []byte)
+}
+
+var groupShapes [256]groupShape
+
+func init() {
+	for c := range groupShapes {
+		n, bytes := getnbytes(uint8(c))
+		groupShapes[c] = groupShape{
+			n:      uint8(n),
+			size:   uint16(n * bytes),
+			unpack: unpackers[bytes],
+		}
+	}
+}
+
+func decodeChunk(dst []int64, ctrl, data []byte, max int) ([]int64, error) {
+	total, size := 0, 0
+	for _, c := range ctrl {
+		g := &groupShapes[c]
+		total += int(g.n)
+		size += int(g.size)
+	}
+	if size != len(data) {
+		return dst, ErrCorrupt
+	}
+	if total > max {
+		return dst, ErrLimit
+	}
+	dst = slices.Grow(dst, total)
+	out := dst[len(dst) : len(dst)+total]
+	for _, c := range ctrl {
+		g := &groupShapes[c]
+		g.unpack(out[:g.n], data[:g.size])
+		out = out[g.n:]
+		data = data[g.size:]
+	}
+	return dst[:len(dst)+total], nil
+}
+
+// unpackers unpack groups of values of each width in bytes, reading
+// exactly len(dst) values from src.
+var unpackers = [9]func(dst []int64, src []byte){
+	0: func(dst []int64, src []byte) {
+		clear(dst)
+	},
+	1: func(dst []int64, src []byte) {
+		src = src[:len(dst)]
+		for j := range dst {
+			dst[j] = unzigzag(uint64(src[j]))
+		}
+	},
+	2: func(dst []int64, src []byte) {
+		src = src[:2*len(dst)]
+		for j := range dst {
+			dst[j] = unzigzag(uint64(le.Uint16(src[2*j:])))
+		}
+	},
+	3: func(dst []int64, src []byte) {
+		src = src[:3*len(dst)]
+		for j := range dst {
+			b := src[3*j : 3*j+3]
+			dst[j] = unzigzag(uint64(le.Uint16(b)) | uint64(b[2])<<16)
+		}
+	},
+	4: func(dst []int64, src []byte) {
+		src = src[:4*len(dst)]
+		for j := range dst {
+			dst[j] = unzigzag(uint64(le.Uint32(src[4*j:])))
+		}
+	},
+	5: func(dst []int64, src []byte) {
+		src = src[:5*len(dst)]
+		for j := range dst {
+			b := src[5*j : 5*j+5]
+			dst[j] = unzigzag(uint64(le.Uint32(b)) | uint64(b[4])<<32)
+		}
+	},
+	6: func(dst []int64, src []byte) {
+		src = src[:6*len(dst)]
+		for j := range dst {
+			b := src[6*j : 6*j+6]
+			dst[j] = unzigzag(uint64(le.Uint32(b)) | uint64(le.Uint16(b[4:]))<<32)
+		}
+	},
+	8: func(dst []int64, src []byte) {
+		src = src[:8*len(dst)]
+		for j := range dst {
+			dst[j] = unzigzag(le.Uint64(src[8*j:]))
+		}
+	},
+}
+
+var le = binary.LittleEndian
@@ -0,0 +1,83 @@
+package varintrle
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSplitLayout(t *testing.T) {
+	vals := randIntSlice(1000)
+	opt := Options{Version: 3}
+	buf := mustEncode(vals, opt)
+	// the same groups as version 1, plus a preamble and chunk sizes
+	if plain := AppendRun(nil, vals); len(buf) > len(plain)+preambleSize+24 {
+		t.Fatalf("expected about %d bytes, got %d", len(plain), len(buf))
+	}
+	actual, err := DecodeRun(nil, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+	if _, err := DecodeRun(nil, buf[:len(buf)-1]); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	w := &bytes.Buffer{}
+	enc := NewEncoderOptions(w, Options{Version: 3, Transform: Delta})
+	err = enc.WriteSlice(vals)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &bytes.Buffer{}
+	dec := NewDecoder(src)
+	actual = actual[:0]
+	for b := w.Bytes(); len(b) > 0; {
+		k := min(rand.Intn(300)+1, len(b))
+		src.Write(b[:k])
+		b = b[k:]
+		for {
+			v, err := dec.Next()
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, v)
+		}
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("Decoder did not match expected output")
+	}
+}
+
+func TestSplitUnpackers(t *testing.T) {
+	src := make([]byte, 32*8)
+	rand.Read(src)
+	for c := 0; c < 256; c++ {
+		g := groupShapes[c]
+		n, bytes := getnbytes(uint8(c))
+		if int(g.n) != n || int(g.size) != n*bytes {
+			t.Fatalf("header %#x: expected %d values in %d bytes, got %d in %d", c, n, n*bytes, g.n, g.size)
+		}
+		// the unpacker must match decodeRun, and must not read past
+		// its group
+		expected, err := decodeRun(nil, append([]byte{uint8(c)}, src[:g.size]...), maxInt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := make([]int64, n)
+		g.unpack(actual, src[:g.size:g.size])
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("header %#x: expected %v, got %v", c, expected, actual)
+		}
+	}
+}
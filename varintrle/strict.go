@@ -0,0 +1,63 @@
+package varintrle
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimit is returned when the input of a decoder holds more values
+// than allowed: more than the max given to DecodeStrict, or more than
+// MaxRepeat allows the others.
+var ErrLimit = errors.New("varintrle: too many values")
+
+// A DecodeError is an error found while decoding, along with the offset
+// of the bad data in the input.
+type DecodeError struct {
+	Offset int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v at offset %d", e.Err, e.Offset)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeStrict is like DecodeRun, but is meant for untrusted input. It
+// never panics, checks every group before decoding any of its values,
+// and decodes no more than max values, since a short repeat run can
+// stand for any number of them. Errors are returned as a *DecodeError,
+// wrapping one of ErrCorrupt, ErrVersion, ErrSignedness, ErrLimit or
+// io.ErrUnexpectedEOF, or the error for a stream of floats.
+func DecodeStrict(dst []int64, src []byte, max int) ([]int64, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil && i > 0 {
+		err = p.check(false)
+	}
+	if err == nil && p.version() > MaxVersion {
+		err = ErrVersion
+	}
+	if err != nil {
+		return dst, &DecodeError{Offset: 0, Err: err}
+	}
+	t := transformer{p: p}
+	s := newScanner(src[i:], p.version())
+	for count := 0; ; {
+		g, ok := s.next()
+		if !ok {
+			if s.err != nil {
+				return dst, &DecodeError{Offset: i + s.hdr, Err: s.err}
+			}
+			return dst, nil
+		}
+		if g.n > max-count {
+			return dst, &DecodeError{Offset: i + s.hdr, Err: ErrLimit}
+		}
+		count += g.n
+		for j := 0; j < g.n; j++ {
+			dst = append(dst, t.undo(unzigzag(g.value(j))))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package varintrle
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadRunFromBytesTruncated(t *testing.T) {
+	vals := []int64{1, 2, 3, 1000, 2000, 0, 0}
+	buf := AppendRun(nil, vals)
+	// the first group is 1 header and 3 payload bytes
+	for k := 0; k < len(buf); k++ {
+		actual := make([]int64, len(vals))
+		n, nb, err := ReadRunFromBytes(actual, buf[:k])
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("%d bytes: unexpected error %v", k, err)
+		}
+		if nb > k || !reflect.DeepEqual(vals[:n], actual[:n]) {
+			t.Fatalf("%d bytes: read %d values from %d bytes", k, n, nb)
+		}
+		// the values returned must take up exactly the bytes read
+		if nb != len(AppendRun(nil, vals[:n])) {
+			t.Fatalf("%d bytes: %d values do not take %d bytes", k, n, nb)
+		}
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	vals := randRepeatSlice(10)
+	buf := mustEncode(vals, Options{Version: 2, Transform: Delta})
+	actual, err := DecodeStrict(nil, buf, len(vals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+
+	repeat := mustEncode(make([]int64, 1000), Options{Version: 2})
+	table := []struct {
+		src    []byte
+		max    int
+		err    error
+		offset int
+	}{
+		{buf, len(vals) - 1, ErrLimit, -1},
+		{AppendRun(nil, []int64{1, 1000})[:4], 2, io.ErrUnexpectedEOF, 2},
+		{repeat, 999, ErrLimit, preambleSize},
+		{append(repeat[:preambleSize:preambleSize], headerRepeat, 0), 1000, ErrCorrupt, preambleSize},
+		{[]byte{preambleHeader, 0, 0x70}, 1, ErrVersion, 0},
+	}
+	for i, tt := range table {
+		_, err := DecodeStrict(nil, tt.src, tt.max)
+		var de *DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, tt.err) {
+			t.Fatalf("%d: expected %v, got %v", i, tt.err, err)
+		}
+		if tt.offset >= 0 && de.Offset != tt.offset {
+			t.Fatalf("%d: expected offset %d, got %d", i, tt.offset, de.Offset)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package table
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"j4k.co/exp/varintrle"
+)
+
+// A Reader reads the columns of a table from an io.ReaderAt, reading
+// only the parts of the file each column needs.
+type Reader struct {
+	r      io.ReaderAt
+	names  []string
+	rows   int64
+	groups []rowGroup
+}
+
+// NewReader returns a Reader for the table of the given size in r. It
+// reads the schema and footer of the table.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	rd := &Reader{r: r}
+	end, err := rd.readSchema(size)
+	if err != nil {
+		return nil, err
+	}
+	if err := rd.readFooter(end, size); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// readSchema reads the schema, and returns the offset at which it ends.
+func (rd *Reader) readSchema(size int64) (int64, error) {
+	br := bufio.NewReader(io.NewSectionReader(rd.r, 0, size))
+	hdr := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return 0, truncated(err)
+	}
+	if string(hdr[:len(magic)]) != magic || hdr[len(magic)] != version {
+		return 0, varintrle.ErrCorrupt
+	}
+	off := int64(headerSize)
+	ncols, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, truncated(err)
+	}
+	off += int64(uvarintLen(ncols))
+	if ncols > uint64(size) {
+		return 0, varintrle.ErrCorrupt
+	}
+	for i := uint64(0); i < ncols; i++ {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return 0, truncated(err)
+		}
+		if n > uint64(size) {
+			return 0, varintrle.ErrCorrupt
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return 0, truncated(err)
+		}
+		off += int64(uvarintLen(n)) + int64(n)
+		rd.names = append(rd.names, string(name))
+	}
+	return off, nil
+}
+
+func (rd *Reader) readFooter(start, size int64) error {
+	if size-start < trailer {
+		return varintrle.ErrTruncated
+	}
+	t := make([]byte, trailer)
+	if _, err := rd.r.ReadAt(t, size-trailer); err != nil {
+		return truncated(err)
+	}
+	if string(t[8:]) != magic {
+		return varintrle.ErrCorrupt
+	}
+	fsize := int64(binary.LittleEndian.Uint32(t))
+	if fsize > size-trailer-start {
+		return varintrle.ErrCorrupt
+	}
+	footer := make([]byte, fsize)
+	if _, err := rd.r.ReadAt(footer, size-trailer-fsize); err != nil {
+		return truncated(err)
+	}
+	if binary.LittleEndian.Uint32(t[4:]) != crc32.Checksum(footer, castagnoli) {
+		return varintrle.ErrChecksum
+	}
+	end := size - trailer - fsize
+	ngroups, i := binary.Uvarint(footer)
+	if i <= 0 || ngroups > uint64(len(footer)) {
+		return varintrle.ErrCorrupt
+	}
+	next := func() int64 {
+		v, k := binary.Uvarint(footer[i:])
+		if k <= 0 || v > uint64(end) {
+			i = -1
+			return 0
+		}
+		i += k
+		return int64(v)
+	}
+	for ; ngroups > 0 && i > 0; ngroups-- {
+		g := rowGroup{rows: int(next())}
+		for range rd.names {
+			c := chunk{offset: next(), size: next()}
+			if i < 0 || c.offset < start || c.size > end-c.offset {
+				return varintrle.ErrCorrupt
+			}
+			g.cols = append(g.cols, c)
+		}
+		rd.rows += int64(g.rows)
+		rd.groups = append(rd.groups, g)
+	}
+	if i != len(footer) {
+		return varintrle.ErrCorrupt
+	}
+	return nil
+}
+
+// Columns returns the names of the columns of the table.
+func (rd *Reader) Columns() []string {
+	return rd.names
+}
+
+// Len returns the number of rows in the table.
+func (rd *Reader) Len() int64 {
+	return rd.rows
+}
+
+// Column appends the values of the named column to dst and returns the
+// extended slice.
+func (rd *Reader) Column(dst []int64, name string) ([]int64, error) {
+	col := -1
+	for i, n := range rd.names {
+		if n == name {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return dst, ErrColumn
+	}
+	var buf []byte
+	for _, g := range rd.groups {
+		c := g.cols[col]
+		if int64(cap(buf)) < c.size {
+			buf = make([]byte, c.size)
+		}
+		buf = buf[:c.size]
+		if _, err := rd.r.ReadAt(buf, c.offset); err != nil {
+			return dst, truncated(err)
+		}
+		vals, err := varintrle.Unmarshal(buf)
+		if err != nil {
+			return dst, err
+		}
+		if len(vals) != g.rows {
+			return dst, varintrle.ErrCorrupt
+		}
+		dst = append(dst, vals...)
+	}
+	return dst, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return varintrle.ErrTruncated
+	}
+	return err
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
@@ -0,0 +1,61 @@
+// Package table stores tables of named int64 columns in a file, with
+// each column encoded by varintrle, so that readers can load only the
+// columns they need.
+//
+// A table file is laid out as
+//
+//	magic    "vrtb"
+//	version  1
+//	schema   uvarint number of columns, then the name of each as a
+//	         uvarint length followed by the name
+//	groups   the row groups, each holding a varintrle container for
+//	         each column in schema order
+//	footer   uvarint number of row groups, then for each, the uvarint
+//	         number of rows, and the uvarint offset and size of each
+//	         of its columns
+//	size     size of the footer, 4 bytes little endian
+//	crc      CRC-32C of the footer, little endian
+//	magic    "vrtb"
+//
+// Rows are buffered as they are written, and written out a row group at
+// a time, so that a file can hold more rows than fit in memory. The
+// footer is written last, on Close.
+package table
+
+import (
+	"errors"
+	"hash/crc32"
+
+	"j4k.co/exp/varintrle"
+)
+
+const (
+	magic      = "vrtb"
+	version    = 1
+	headerSize = 5  // magic and version
+	trailer    = 12 // size, crc and magic
+
+	// DefaultRowGroupSize is the number of rows in each row group, if
+	// not set in Options.
+	DefaultRowGroupSize = 1 << 16
+)
+
+var (
+	// ErrColumn is returned when reading a column that is not in the
+	// table.
+	ErrColumn = errors.New("table: no such column")
+
+	errRowCount = errors.New("table: columns differ in length")
+	errClosed   = errors.New("table: writer is closed")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// A Column describes a column of a table.
+type Column struct {
+	Name string
+
+	// Options control how the column is encoded. BlockSize and Index
+	// are set by the Writer.
+	Options varintrle.Options
+}
@@ -0,0 +1,124 @@
+package table
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"j4k.co/exp/varintrle"
+)
+
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+func TestTable(t *testing.T) {
+	cols := []Column{
+		{Name: "time", Options: varintrle.Options{Transform: varintrle.DeltaOfDelta}},
+		{Name: "value"},
+		{Name: "count", Options: varintrle.Options{Transform: varintrle.Delta, Version: 2}},
+	}
+	const rows = 2500
+	data := make([][]int64, len(cols))
+	for i := 0; i < rows; i++ {
+		data[0] = append(data[0], 1400000000+int64(i)*10)
+		data[1] = append(data[1], rand.Int63n(1<<40)-1<<39)
+		data[2] = append(data[2], int64(i/7))
+	}
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, cols, Options{RowGroupSize: 1000})
+	for i := 0; i < rows; i += 300 {
+		j := min(i+300, rows)
+		if err := w.Write(data[0][i:j], data[1][i:j], data[2][i:j]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Write(data[0][:2], data[1][:1], data[2][:2]); err != errRowCount {
+		t.Fatalf("expected errRowCount, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	r, err := NewReader(cr, int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Columns(), []string{"time", "value", "count"}) || r.Len() != rows {
+		t.Fatalf("unexpected columns %v or length %d", r.Columns(), r.Len())
+	}
+	if len(r.groups) != 3 {
+		t.Fatalf("expected 3 row groups, got %d", len(r.groups))
+	}
+	cr.n = 0
+	for i, c := range cols {
+		vals, err := r.Column(nil, c.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(data[i], vals) {
+			t.Fatalf("column %s did not match expected output", c.Name)
+		}
+		if c.Name == "time" && cr.n > buf.Len()/4 {
+			t.Fatalf("reading one column read %d of %d bytes", cr.n, buf.Len())
+		}
+	}
+	if _, err := r.Column(nil, "missing"); err != ErrColumn {
+		t.Fatalf("expected ErrColumn, got %v", err)
+	}
+
+	damaged := append([]byte(nil), buf.Bytes()...)
+	damaged[len(damaged)-trailer-1] ^= 1
+	if _, err := NewReader(bytes.NewReader(damaged), int64(len(damaged))); err != varintrle.ErrChecksum {
+		t.Fatalf("expected ErrChecksum, got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader(damaged[:20]), 20); err == nil {
+		t.Fatalf("expected an error for a truncated table")
+	}
+}
+
+func TestWriterBatches(t *testing.T) {
+	cols := []Column{{Name: "a"}, {Name: "b", Options: varintrle.Options{Transform: varintrle.Delta}}}
+	const rows = 2345
+	data := make([][]int64, len(cols))
+	for i := 0; i < rows; i++ {
+		data[0] = append(data[0], int64(i%13))
+		data[1] = append(data[1], int64(i*i))
+	}
+	var expected []byte
+	// the table is the same no matter how the rows are split up
+	for _, batch := range []int{rows, 1, 999, 1000, 1001, 2100} {
+		buf := &bytes.Buffer{}
+		w := NewWriter(buf, cols, Options{RowGroupSize: 1000})
+		for i := 0; i < rows; i += batch {
+			j := min(i+batch, rows)
+			if err := w.Write(data[0][i:j], data[1][i:j]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if expected == nil {
+			expected = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), expected) {
+			t.Fatalf("batches of %d: table differs", batch)
+		}
+	}
+	r, err := NewReader(bytes.NewReader(expected), int64(len(expected)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals, err := r.Column(nil, "b"); err != nil || !reflect.DeepEqual(vals, data[1]) || len(r.groups) != 3 {
+		t.Fatalf("unexpected column b or %d row groups: %v", len(r.groups), err)
+	}
+}
@@ -0,0 +1,195 @@
+package table
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"j4k.co/exp/varintrle"
+)
+
+// Options control how a table is written.
+type Options struct {
+	// RowGroupSize is the number of rows in each row group. Zero means
+	// DefaultRowGroupSize.
+	RowGroupSize int
+}
+
+// A Writer writes a table to an output stream.
+type Writer struct {
+	w       io.Writer
+	err     error
+	cols    []Column
+	size    int
+	buf     [][]int64
+	off     int64
+	started bool
+	groups  []rowGroup
+}
+
+type rowGroup struct {
+	rows int
+	cols []chunk
+}
+
+type chunk struct {
+	offset int64
+	size   int64
+}
+
+// NewWriter returns a new Writer writing a table of the given columns
+// to w.
+func NewWriter(w io.Writer, cols []Column, opt Options) *Writer {
+	size := opt.RowGroupSize
+	if size <= 0 {
+		size = DefaultRowGroupSize
+	}
+	return &Writer{
+		w:    w,
+		cols: cols,
+		size: size,
+		buf:  make([][]int64, len(cols)),
+	}
+}
+
+// Write appends a batch of rows, given as the values of each column in
+// the order of the schema. All columns must hold the same number of
+// values.
+func (w *Writer) Write(cols ...[]int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(cols) != len(w.cols) {
+		return errRowCount
+	}
+	for _, c := range cols {
+		if len(c) != len(cols[0]) {
+			return errRowCount
+		}
+	}
+	if len(w.cols) == 0 {
+		return nil
+	}
+	// fill up the rows already buffered first, then write out whole
+	// row groups straight from cols, and buffer the rest
+	off := 0
+	if len(w.buf[0]) > 0 {
+		off = min(w.size-len(w.buf[0]), len(cols[0]))
+		for i, c := range cols {
+			w.buf[i] = append(w.buf[i], c[:off]...)
+		}
+		if len(w.buf[0]) < w.size {
+			return nil
+		}
+		if err := w.flush(w.buf); err != nil {
+			return err
+		}
+		for i := range w.buf {
+			w.buf[i] = w.buf[i][:0]
+		}
+	}
+	rows := make([][]int64, len(cols))
+	for ; len(cols[0])-off >= w.size; off += w.size {
+		for i, c := range cols {
+			rows[i] = c[off : off+w.size]
+		}
+		if err := w.flush(rows); err != nil {
+			return err
+		}
+	}
+	for i, c := range cols {
+		w.buf[i] = append(w.buf[i], c[off:]...)
+	}
+	return nil
+}
+
+// flush writes out rows, which holds the values of each column, as a
+// row group.
+func (w *Writer) flush(rows [][]int64) error {
+	if !w.started {
+		w.started = true
+		if err := w.write(appendSchema(nil, w.cols)); err != nil {
+			return err
+		}
+	}
+	n := 0
+	if len(rows) > 0 {
+		n = len(rows[0])
+	}
+	if n == 0 {
+		return nil
+	}
+	g := rowGroup{rows: n}
+	for i, col := range w.cols {
+		opt := col.Options
+		opt.BlockSize = 0
+		opt.Index = false
+		data, err := varintrle.Marshal(rows[i], opt)
+		if err != nil {
+			w.err = err
+			return err
+		}
+		g.cols = append(g.cols, chunk{offset: w.off, size: int64(len(data))})
+		if err := w.write(data); err != nil {
+			return err
+		}
+	}
+	w.groups = append(w.groups, g)
+	return nil
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.w.Write(data)
+	w.off += int64(n)
+	if err != nil {
+		w.err = err
+	}
+	return err
+}
+
+// Close writes out the rows still buffered and the footer of the
+// table. Further writes return an error. Close does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err == errClosed {
+		return nil
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flush(w.buf); err != nil {
+		return err
+	}
+	if err := w.write(appendFooter(nil, w.groups)); err != nil {
+		return err
+	}
+	w.err = errClosed
+	return nil
+}
+
+func appendSchema(dst []byte, cols []Column) []byte {
+	dst = append(dst, magic...)
+	dst = append(dst, version)
+	dst = binary.AppendUvarint(dst, uint64(len(cols)))
+	for _, c := range cols {
+		dst = binary.AppendUvarint(dst, uint64(len(c.Name)))
+		dst = append(dst, c.Name...)
+	}
+	return dst
+}
+
+func appendFooter(dst []byte, groups []rowGroup) []byte {
+	start := len(dst)
+	dst = binary.AppendUvarint(dst, uint64(len(groups)))
+	for _, g := range groups {
+		dst = binary.AppendUvarint(dst, uint64(g.rows))
+		for _, c := range g.cols {
+			dst = binary.AppendUvarint(dst, uint64(c.offset))
+			dst = binary.AppendUvarint(dst, uint64(c.size))
+		}
+	}
+	size := len(dst) - start
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(size))
+	dst = binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[start:start+size], castagnoli))
+	return append(dst, magic...)
+}
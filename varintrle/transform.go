@@ -0,0 +1,181 @@
+package varintrle
+
+import "io"
+
+// A Transform turns values into residuals that are usually smaller, and
+// so take fewer bytes to encode. The transform used is recorded in the
+// stream, and undone when decoding.
+type Transform uint8
+
+const (
+	// NoTransform encodes values as they are.
+	NoTransform Transform = iota
+	// Delta encodes the difference between each value and the one
+	// before it, which suits counters and other growing values.
+	Delta
+	// DeltaOfDelta encodes the difference between consecutive deltas,
+	// as in Facebook's Gorilla, which suits timestamps taken at a
+	// regular interval.
+	DeltaOfDelta
+	// FrameOfReference encodes the difference between each value and a
+	// reference value, which suits values that are large but close
+	// together.
+	FrameOfReference
+)
+
+// Options control how values are encoded. The zero Options encodes
+// plain streams, the same as WriteRun.
+type Options struct {
+	// Transform is applied to values before they are encoded.
+	Transform Transform
+
+	// Version is the version of the format to write. Version 2 adds
+	// repeat runs, so that runs of the same value take O(1) space
+	// no matter the value, and groups packed to a width in bits, for
+	// small values. Version 3 holds the groups of version 1 in
+	// chunks, with their headers ahead of their payloads. Zero means
+	// version 1.
+	Version int
+
+	// BlockSize is the number of values in each block written by
+	// Marshal. Zero means DefaultBlockSize.
+	BlockSize int
+
+	// Index adds an index of the blocks written by Marshal, so that a
+	// Reader can find them without reading through the data.
+	Index bool
+}
+
+// plain reports whether opt encodes plain streams.
+func (opt Options) plain() bool {
+	return opt.Transform == NoTransform && opt.Version <= 1
+}
+
+// check returns ErrVersion if opt asks for a version of the format that
+// is not supported, and ErrTransform if it asks for an unknown
+// transform.
+func (opt Options) check() error {
+	if opt.Version < 0 || opt.Version > MaxVersion {
+		return ErrVersion
+	}
+	if opt.Transform > FrameOfReference {
+		return ErrTransform
+	}
+	return nil
+}
+
+// WriteRunOptions is like WriteRun, but encodes vals as described by
+// opt.
+func WriteRunOptions(w io.Writer, vals []int64, opt Options) error {
+	if err := opt.check(); err != nil {
+		return err
+	}
+	if len(vals) == 0 && opt.plain() {
+		return nil
+	}
+	_, err := w.Write(appendRunOptions(nil, vals, opt))
+	return err
+}
+
+// AppendRunOptions is like AppendRun, but encodes vals as described by
+// opt. Streams with a FrameOfReference transform use the midpoint of
+// vals as the reference. If opt.Version is not supported, or
+// opt.Transform is unknown, dst is returned as is, along with
+// ErrVersion or ErrTransform.
+func AppendRunOptions(dst []byte, vals []int64, opt Options) ([]byte, error) {
+	if err := opt.check(); err != nil {
+		return dst, err
+	}
+	return appendRunOptions(dst, vals, opt), nil
+}
+
+// appendRunOptions is AppendRunOptions for options that were checked.
+func appendRunOptions(dst []byte, vals []int64, opt Options) []byte {
+	if opt.plain() {
+		return AppendRun(dst, vals)
+	}
+	p := makePreamble(opt)
+	if opt.Transform == FrameOfReference {
+		p.ref = midpoint(vals)
+	}
+	dst = appendPreamble(dst, p)
+	return appendGroups(dst, vals, p)
+}
+
+// appendGroups appends the groups of vals, written as described by p.
+func appendGroups(dst []byte, vals []int64, p preamble) []byte {
+	t := transformer{p: p}
+	pk := newPacker(p.version())
+	for _, v := range vals {
+		dst = pk.add(dst, zigzag(t.apply(v)))
+	}
+	return pk.end(dst)
+}
+
+// midpoint returns the value halfway between the smallest and largest
+// of vals, so that residuals from it are as small as possible.
+func midpoint(vals []int64) int64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	min, max := vals[0], vals[0]
+	for _, v := range vals[1:] {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min + int64((uint64(max)-uint64(min))/2)
+}
+
+// transformer applies or undoes the transform of a stream, one value
+// at a time.
+type transformer struct {
+	p     preamble
+	n     int
+	prev  int64
+	delta int64
+}
+
+func (t *transformer) apply(v int64) int64 {
+	switch t.p.transform() {
+	case Delta:
+		r := v - t.prev
+		t.prev = v
+		return r
+	case DeltaOfDelta:
+		// the first value is stored as is, and the second as a delta
+		d := v - t.prev
+		r := d - t.delta
+		t.prev = v
+		if t.n > 0 {
+			t.delta = d
+		}
+		t.n++
+		return r
+	case FrameOfReference:
+		return v - t.p.ref
+	}
+	return v
+}
+
+func (t *transformer) undo(r int64) int64 {
+	switch t.p.transform() {
+	case Delta:
+		t.prev += r
+		return t.prev
+	case DeltaOfDelta:
+		d := r + t.delta
+		t.prev += d
+		if t.n > 0 {
+			t.delta = d
+		}
+		t.n++
+		return t.prev
+	case FrameOfReference:
+		return r + t.p.ref
+	}
+	return r
+}
@@ -0,0 +1,133 @@
+package varintrle
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// mustEncode is AppendRunOptions, for options known to be valid.
+func mustEncode(vals []int64, opt Options) []byte {
+	buf, err := AppendRunOptions(nil, vals, opt)
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+func timestamps(n int) []int64 {
+	vals := make([]int64, n)
+	t := int64(1400000000000)
+	for i := range vals {
+		t += 1000
+		if i%10 == 0 {
+			t += 3
+		}
+		vals[i] = t
+	}
+	return vals
+}
+
+func TestTransforms(t *testing.T) {
+	inputs := [][]int64{
+		timestamps(200),
+		randIntSlice(20),
+		{},
+		{-1 << 63, 1<<63 - 1, 0, -1 << 63},
+	}
+	plain := AppendRun(nil, inputs[0])
+	for _, tr := range []Transform{Delta, DeltaOfDelta, FrameOfReference} {
+		opt := Options{Transform: tr}
+		for _, vals := range inputs {
+			buf := mustEncode(vals, opt)
+			actual, err := DecodeRun(nil, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vals) != len(actual) || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+				t.Fatalf("transform %d: did not match expected output", tr)
+			}
+
+			// the Encoder picks a different reference for
+			// FrameOfReference, but must read back the same
+			w := &bytes.Buffer{}
+			enc := NewEncoderOptions(w, opt)
+			err = enc.WriteSlice(vals)
+			if err == nil {
+				err = enc.Close()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := NewDecoder(w)
+			actual = actual[:0]
+			for v := range dec.All() {
+				actual = append(actual, v)
+			}
+			if dec.Err() != nil {
+				t.Fatal(dec.Err())
+			}
+			if len(vals) != len(actual) || len(vals) > 0 && !reflect.DeepEqual(vals, actual) {
+				t.Fatalf("transform %d: Decoder did not match expected output", tr)
+			}
+		}
+		if tr == Delta || tr == DeltaOfDelta {
+			buf := mustEncode(inputs[0], opt)
+			if len(buf)*2 > len(plain) {
+				t.Fatalf("transform %d: expected timestamps to shrink, got %d bytes from %d", tr, len(buf), len(plain))
+			}
+		}
+	}
+}
+
+func TestTransformPlainReaders(t *testing.T) {
+	buf := mustEncode([]int64{1, 2, 3}, Options{Transform: Delta})
+	if _, err := ReadRun(make([]int64, 3), bytes.NewReader(buf)); err != ErrNotPlain {
+		t.Fatalf("ReadRun: expected ErrNotPlain, got %v", err)
+	}
+	if _, _, err := ReadRunFromBytes(make([]int64, 3), buf); err != ErrNotPlain {
+		t.Fatalf("ReadRunFromBytes: expected ErrNotPlain, got %v", err)
+	}
+}
+
+func TestOptionsVersion(t *testing.T) {
+	opt := Options{Version: MaxVersion + 1}
+	vals := []int64{1, 2, 3}
+	if buf, err := AppendRunOptions([]byte{1}, vals, opt); err != ErrVersion || len(buf) != 1 {
+		t.Fatalf("AppendRunOptions: expected ErrVersion, got %v", err)
+	}
+	if _, err := Marshal(vals, opt); err != ErrVersion {
+		t.Fatalf("Marshal: expected ErrVersion, got %v", err)
+	}
+	if err := WriteRunOptions(&bytes.Buffer{}, vals, opt); err != ErrVersion {
+		t.Fatalf("WriteRunOptions: expected ErrVersion, got %v", err)
+	}
+	enc := NewEncoderOptions(&bytes.Buffer{}, opt)
+	if err := enc.Write(1); err != ErrVersion {
+		t.Fatalf("Encoder: expected ErrVersion, got %v", err)
+	}
+	if err := enc.Close(); err != ErrVersion {
+		t.Fatalf("Encoder: expected ErrVersion from Close, got %v", err)
+	}
+}
+
+func TestOptionsTransform(t *testing.T) {
+	vals := []int64{1, 2, 3}
+	// transforms would spill into the other flags of the preamble
+	for _, tr := range []Transform{4, 8} {
+		opt := Options{Transform: tr}
+		if buf, err := AppendRunOptions([]byte{1}, vals, opt); err != ErrTransform || len(buf) != 1 {
+			t.Fatalf("transform %d: expected ErrTransform, got %v", tr, err)
+		}
+		if err := WriteRunOptions(&bytes.Buffer{}, vals, opt); err != ErrTransform {
+			t.Fatalf("transform %d: WriteRunOptions: expected ErrTransform, got %v", tr, err)
+		}
+		enc := NewEncoderOptions(&bytes.Buffer{}, opt)
+		if err := enc.Write(1); err != ErrTransform {
+			t.Fatalf("transform %d: Encoder: expected ErrTransform, got %v", tr, err)
+		}
+		if err := enc.Close(); err != ErrTransform {
+			t.Fatalf("transform %d: Encoder: expected ErrTransform from Close, got %v", tr, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package varintrle
+
+import "io"
+
+// WriteRunUint64 is like WriteRun, but for unsigned values. The values
+// are stored as is rather than zigzag encoded, which saves a bit per
+// value. The stream is marked as unsigned, and can only be read back
+// with ReadRunUint64 or DecodeRunUint64.
+func WriteRunUint64(w io.Writer, vals []uint64) error {
+	_, err := w.Write(AppendRunUint64(nil, vals))
+	return err
+}
+
+// AppendRunUint64 is like AppendRun, but for unsigned values. See
+// WriteRunUint64.
+func AppendRunUint64(dst []byte, vals []uint64) []byte {
+	dst = appendPreamble(dst, preamble{flags: flagUnsigned})
+	var rw runWriter
+	for _, v := range vals {
+		dst = rw.add(dst, v)
+	}
+	return rw.end(dst)
+}
+
+// ReadRunUint64 is like ReadRun, but for streams written by
+// WriteRunUint64. Reading any other stream returns ErrSignedness.
+func ReadRunUint64(vals []uint64, r io.Reader) (int, error) {
+	return readRun(vals, r, true)
+}
+
+// DecodeRunUint64 is like DecodeRun, but for streams written by
+// AppendRunUint64. Decoding any other stream returns ErrSignedness.
+func DecodeRunUint64(dst []uint64, src []byte) ([]uint64, error) {
+	p, i, err := parsePreamble(src)
+	if err == nil && i > 0 {
+		err = p.checkPlain(true)
+	}
+	if err == nil && i == 0 && len(src) > 0 {
+		err = ErrSignedness
+	}
+	if err != nil {
+		return dst, err
+	}
+	for i < len(src) {
+		n, bytes := getnbytes(src[i])
+		i++
+		if bytes == 0 {
+			for ; n > 0; n-- {
+				dst = append(dst, 0)
+			}
+			continue
+		}
+		if len(src)-i < n*bytes {
+			return dst, io.ErrUnexpectedEOF
+		}
+		mask := payloadMask[bytes]
+		for ; n > 0; n-- {
+			dst = append(dst, readPayload(src, i, bytes, mask))
+			i += bytes
+		}
+	}
+	return dst, nil
+}
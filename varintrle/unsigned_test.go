@@ -0,0 +1,58 @@
+package varintrle
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestUnsigned(t *testing.T) {
+	vals := []uint64{0, 0, 0x80, 0xff, 1 << 63, 7, 0}
+	buf := &bytes.Buffer{}
+	err := WriteRunUint64(buf, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0x80 and 0xff fit in one byte without zigzag
+	if !bytes.Contains(buf.Bytes(), []byte{nbytes(2, 1), 0x80, 0xff}) {
+		t.Fatalf("expected 0x80 and 0xff to take one byte each")
+	}
+	actual, err := DecodeRunUint64(nil, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+	actual = make([]uint64, len(vals))
+	n, err := ReadRunUint64(actual, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(vals) || !reflect.DeepEqual(vals, actual) {
+		t.Fatalf("did not match expected output")
+	}
+}
+
+func TestUnsignedSignedness(t *testing.T) {
+	unsigned := AppendRunUint64(nil, []uint64{1, 2, 3})
+	signed := AppendRun(nil, []int64{1, 2, 3})
+	if _, err := DecodeRun(nil, unsigned); err != ErrSignedness {
+		t.Fatalf("DecodeRun: expected ErrSignedness, got %v", err)
+	}
+	if _, err := ReadRun(make([]int64, 3), bytes.NewReader(unsigned)); err != ErrSignedness {
+		t.Fatalf("ReadRun: expected ErrSignedness, got %v", err)
+	}
+	if _, _, err := ReadRunFromBytes(make([]int64, 3), unsigned); err != ErrSignedness {
+		t.Fatalf("ReadRunFromBytes: expected ErrSignedness, got %v", err)
+	}
+	if _, err := NewDecoder(bytes.NewReader(unsigned)).Next(); err != ErrSignedness {
+		t.Fatalf("Decoder: expected ErrSignedness, got %v", err)
+	}
+	if _, err := DecodeRunUint64(nil, signed); err != ErrSignedness {
+		t.Fatalf("DecodeRunUint64: expected ErrSignedness, got %v", err)
+	}
+	if _, err := ReadRunUint64(make([]uint64, 3), bytes.NewReader(signed)); err != ErrSignedness {
+		t.Fatalf("ReadRunUint64: expected ErrSignedness, got %v", err)
+	}
+}
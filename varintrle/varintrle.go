@@ -4,13 +4,25 @@
 // TODO: get into detail on the format. described briefly in WriteTo's
 // comment.
 //
-// TODO: re-design to be friendly to vector and parallel computation.
+// Version 3 of the format keeps the group headers apart from their
+// payloads, so that DecodeRun can unpack it a group at a time, several
+// times faster than earlier versions. EncodeParallel and DecodeParallel
+// encode and decode version 1 streams on several goroutines at once.
+//
+// Once a version of the format is released, what its streams mean never
+// changes: new kinds of groups, or new meanings for headers, come with
+// a new version, and decoders return ErrVersion for versions later than
+// MaxVersion. Headers a version does not define are ErrCorrupt, never
+// skipped. Versions 2 and 3 were not released until the bit-packed
+// groups of version 2 were defined, so no decoder of version 2 lacks
+// them.
 package varintrle
 
 import (
 	"bytes"
 	"errors"
 	"io"
+	"math/bits"
 )
 
 var getnbytesLUT = [8]int{0, 1, 2, 3, 4, 5, 6, 8}
@@ -32,6 +44,13 @@ func getnbytes(b uint8) (n, bytes int) {
 	return int((b>>3)&0x1f) + 1, getnbytesLUT[b & 0x7]
 }
 
+// widthOf returns the number of bytes needed for v. Note that values
+// needing 7 bytes are stored in 8, as the header has no room for 7, but
+// are still grouped apart from 8 byte values.
+func widthOf(v uint64) int {
+	return (bits.Len64(v) + 7) >> 3
+}
+
 func zigzag(x int64) uint64 {
 	ux := uint64(x) << 1
 	if x < 0 {
@@ -117,10 +136,18 @@ func WriteRun(w io.Writer, vals []int64) error {
 // ReadRun reads all integers read from r into vals, up to len(vals),
 // and returns the number of values read. If number of values read is
 // less than len(vals), an error is returned. If the bytes decode into
-// more values than len(vals), an error is returned.
+// more values than len(vals), an error is returned. Streams written
+// with a Transform or a later version of the format return ErrNotPlain;
+// use DecodeRun or a Decoder for them.
 func ReadRun(vals []int64, r io.Reader) (int, error) {
+	return readRun(vals, r, false)
+}
+
+func readRun[T int64 | uint64](vals []T, r io.Reader, unsigned bool) (int, error) {
 	var buf [8]byte
 	pos := 0
+	// set while the stream may still begin with a preamble
+	maybePreamble := true
 	for {
 		if pos >= len(vals) {
 			return pos, nil
@@ -132,6 +159,12 @@ func ReadRun(vals []int64, r io.Reader) (int, error) {
 			}
 			return pos, err
 		}
+		if maybePreamble && buf[0] != preambleHeader {
+			maybePreamble = false
+			if unsigned {
+				return pos, ErrSignedness
+			}
+		}
 		n, bytes := getnbytes(buf[0])
 		if pos+n > len(vals) {
 			return pos, errors.New("varintrle: unexpected values to read")
@@ -152,29 +185,61 @@ func ReadRun(vals []int64, r io.Reader) (int, error) {
 			for j := 0; j < bytes; j++ {
 				val |= uint64(buf[j]) << (uint64(j) * 8)
 			}
-			vals[pos] = unzigzag(val)
+			if maybePreamble {
+				maybePreamble = false
+				if val == 0 {
+					_, err = io.ReadFull(r, buf[:1])
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					if err == nil {
+						err = preamble{flags: buf[0]}.checkPlain(unsigned)
+					}
+					if err != nil {
+						return pos, err
+					}
+					break
+				}
+				if unsigned {
+					return pos, ErrSignedness
+				}
+			}
+			if unsigned {
+				vals[pos] = T(val)
+			} else {
+				vals[pos] = T(unzigzag(val))
+			}
 			pos++
 		}
 	}
 }
 
-// ReadRunFromBytes
+// ReadRunFromBytes decodes the values in buf into vals, up to
+// len(vals), and returns the number of values decoded and the number of
+// bytes of buf they took up. Decoding stops at the first group that does
+// not fit in vals, and if buf ends in the middle of a group, that group
+// is left unread and io.ErrUnexpectedEOF is returned. Like ReadRun, it
+// returns ErrNotPlain for streams it cannot undo.
 func ReadRunFromBytes(vals []int64, buf []byte) (valsParsed int, bytesRead int, err error) {
+	p, index, err := parsePreamble(buf)
+	if err == nil && index > 0 {
+		err = p.checkPlain(false)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
 	pos := 0
-	index := 0
-	for {
-		if pos >= len(vals) {
-			break
-		}
-		if len(buf) <= 0 {
-			break
-		}
+	for pos < len(vals) && index < len(buf) {
 		n, bytes := getnbytes(buf[index])
-		index++
 		if pos+n > len(vals) {
 			err = errors.New("varintrle: unexpected values to read")
 			break
 		}
+		if len(buf)-index-1 < bytes*n {
+			err = io.ErrUnexpectedEOF
+			break
+		}
+		index++
 		if bytes == 0 {
 			for i := 0; i < n; i++ {
 				vals[pos+i] = 0
@@ -182,10 +247,6 @@ func ReadRunFromBytes(vals []int64, buf []byte) (valsParsed int, bytesRead int,
 			pos += n
 			continue
 		}
-		if len(buf) - index < bytes*n {
-			err = io.EOF
-			break
-		}
 		for i := 0; i < n; i++ {
 			var val uint64
 			for j := 0; j < bytes; j++ {
@@ -196,7 +257,5 @@ func ReadRunFromBytes(vals []int64, buf []byte) (valsParsed int, bytesRead int,
 			pos++
 		}
 	}
-	valsParsed = pos
-	bytesRead = index
-	return
+	return pos, index, err
 }
@@ -161,3 +161,20 @@ func BenchmarkReadVarintRLERandom2(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkReadVarintRLERandom3 reads the values of
+// BenchmarkReadVarintRLERandom2 from a version 3 stream, whose groups
+// are unpacked whole.
+func BenchmarkReadVarintRLERandom3(b *testing.B) {
+	rand.Seed(1)
+	vals := randIntSlice(100)
+	b.SetBytes(100 * 8)
+	buf := mustEncode(vals, Options{Version: 3})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := DecodeRun(vals[:0], buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}